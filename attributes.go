@@ -0,0 +1,247 @@
+package acd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// The keys of the properties recording the attributes of an uploaded local
+// file, owned by Config.PropertiesOwner. See UploadOptions.Preserve and
+// DownloadOptions.Preserve.
+const (
+	// PropertyModTime is the modification time in RFC 3339 format.
+	PropertyModTime = "mtime"
+
+	// PropertyMode is the permission bits in octal.
+	PropertyMode = "mode"
+
+	// PropertyUser and PropertyGroup are the names of the owner and the group
+	// of the file, or their IDs if they have no name.
+	PropertyUser  = "user"
+	PropertyGroup = "group"
+
+	// PropertyXattrs is a JSON object of the extended attributes, their
+	// values being base64-encoded.
+	PropertyXattrs = "xattrs"
+)
+
+// maxPropertyValue is the length of the longest property value Amazon
+// accepts.
+const maxPropertyValue = 500
+
+// checkPreserve returns ErrNoPropertiesOwner if opts.Preserve is set while
+// the owner of the properties is not configured, before anything is uploaded.
+func (c *Client) checkPreserve(opts *UploadOptions) error {
+	if opts.Preserve && c.PropertiesOwner() == "" {
+		log.Errorf("%s: the attributes of the files cannot be recorded", constants.ErrNoPropertiesOwner)
+		return constants.ErrNoPropertiesOwner
+	}
+
+	return nil
+}
+
+// recordAttributes records the attributes of the local file of ft in the
+// properties of the node uploaded at ft.remotePath, or of its manifest, if
+// opts.Preserve is set. Only the properties which changed are written.
+func (c *Client) recordAttributes(ft fileTransfer, opts *UploadOptions) error {
+	if !opts.Preserve || ft.localPath == "" {
+		return nil
+	}
+	owner := c.PropertiesOwner()
+	info, err := os.Lstat(ft.localPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrStatFile, err)
+		return constants.ErrStatFile
+	}
+	// nothing was uploaded at ft.remotePath if the file was deduplicated.
+	n, err := c.findSilently(ft.remotePath)
+	if err != nil {
+		if n, err = c.findSilently(ft.remotePath + ChunkedSuffix); err != nil {
+			return nil
+		}
+	}
+
+	for key, value := range fileAttributes(ft.localPath, info, opts.Xattrs) {
+		if current, found := n.Property(owner, key); found && current == value {
+			continue
+		}
+		if err := n.SetProperty(owner, key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileAttributes returns the properties recording the attributes of the local
+// file name, along with its extended attributes if xattrs is set.
+func fileAttributes(name string, info os.FileInfo, xattrs bool) map[string]string {
+	attrs := map[string]string{
+		PropertyModTime: info.ModTime().UTC().Format(time.RFC3339Nano),
+		PropertyMode:    strconv.FormatUint(uint64(info.Mode().Perm()), 8),
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		uid, gid := strconv.FormatUint(uint64(stat.Uid), 10), strconv.FormatUint(uint64(stat.Gid), 10)
+		attrs[PropertyUser], attrs[PropertyGroup] = uid, gid
+		if u, err := user.LookupId(uid); err == nil {
+			attrs[PropertyUser] = u.Username
+		}
+		if name, found := lookupGroupFile(gid, 2, 0); found {
+			attrs[PropertyGroup] = name
+		}
+	}
+	if !xattrs {
+		return attrs
+	}
+
+	values, err := getXattrs(name)
+	if err != nil || len(values) == 0 {
+		return attrs
+	}
+	encoded := make(map[string]string)
+	for k, v := range values {
+		encoded[k] = base64.StdEncoding.EncodeToString(v)
+	}
+	if b, err := json.Marshal(encoded); err == nil && len(b) <= maxPropertyValue {
+		attrs[PropertyXattrs] = string(b)
+	} else {
+		log.Infof("the extended attributes of %q are too large to be recorded, skipping them", name)
+	}
+
+	return attrs
+}
+
+// restoreAttributes restores the attributes recorded in the properties of
+// ft.node on the downloaded file ft.localPath if opts.Preserve is set. The
+// modification time falls back to the content date then to the modification
+// date of the node, the mode and the owner are left alone if they were not
+// recorded. Failing to change the owner, as a user other than root does, is
+// not an error.
+func (c *Client) restoreAttributes(ft fileTransfer, opts *DownloadOptions) error {
+	if !opts.Preserve {
+		return nil
+	}
+	var props map[string]string
+	if owner := c.PropertiesOwner(); owner != "" {
+		props = ft.node.Properties[owner]
+		if props == nil {
+			// the properties of the node may not be cached, a failure only
+			// means falling back to the node dates.
+			props, _ = ft.node.FetchProperties(owner)
+		}
+	}
+
+	mtime := ft.node.ContentProperties.ContentDate
+	if mtime.IsZero() {
+		mtime = ft.node.ModifiedDate
+	}
+	if t, err := time.Parse(time.RFC3339Nano, props[PropertyModTime]); err == nil {
+		mtime = t
+	}
+	if mode, err := strconv.ParseUint(props[PropertyMode], 8, 32); err == nil {
+		if err := os.Chmod(ft.localPath, os.FileMode(mode).Perm()); err != nil {
+			log.Errorf("%s: %s", constants.ErrWritingMetadata, err)
+			return constants.ErrWritingMetadata
+		}
+	}
+	uid, gid := lookupUser(props[PropertyUser]), lookupGroup(props[PropertyGroup])
+	if uid != -1 || gid != -1 {
+		if err := os.Lchown(ft.localPath, uid, gid); err != nil {
+			log.Debugf("cannot change the owner of %q: %s", ft.localPath, err)
+		}
+	}
+	if opts.Xattrs && props[PropertyXattrs] != "" {
+		var encoded map[string]string
+		if err := json.Unmarshal([]byte(props[PropertyXattrs]), &encoded); err != nil {
+			log.Errorf("%s: %s", constants.ErrJSONDecoding, err)
+			return constants.ErrJSONDecoding
+		}
+		for k, v := range encoded {
+			value, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				log.Errorf("%s: %s", constants.ErrJSONDecoding, err)
+				return constants.ErrJSONDecoding
+			}
+			if err := setXattr(ft.localPath, k, value); err != nil {
+				log.Debugf("cannot set the extended attribute %s of %q: %s", k, ft.localPath, err)
+			}
+		}
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(ft.localPath, mtime, mtime); err != nil {
+			log.Errorf("%s: %s", constants.ErrWritingMetadata, err)
+			return constants.ErrWritingMetadata
+		}
+	}
+
+	return nil
+}
+
+// lookupUser returns the ID of the user name, which may be an ID itself, or
+// -1 if it is unknown.
+func lookupUser(name string) int {
+	if name == "" {
+		return -1
+	}
+	if u, err := user.Lookup(name); err == nil {
+		name = u.Uid
+	}
+	id, err := strconv.Atoi(name)
+	if err != nil {
+		return -1
+	}
+
+	return id
+}
+
+// lookupGroup returns the ID of the group name, which may be an ID itself, or
+// -1 if it is unknown.
+func lookupGroup(name string) int {
+	if name == "" {
+		return -1
+	}
+	if gid, found := lookupGroupFile(name, 0, 2); found {
+		name = gid
+	}
+	id, err := strconv.Atoi(name)
+	if err != nil {
+		return -1
+	}
+
+	return id
+}
+
+// groupFile is the group database read by lookupGroupFile.
+const groupFile = "/etc/group"
+
+// lookupGroupFile returns the field ret of the first entry of the group
+// database whose field key is value, the fields being the name, the password,
+// the ID and the members of the group. os/user cannot look groups up before
+// Go 1.7.
+func lookupGroupFile(value string, key, ret int) (string, bool) {
+	b, err := ioutil.ReadFile(groupFile)
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields[key] == value {
+			return fields[ret], true
+		}
+	}
+
+	return "", false
+}
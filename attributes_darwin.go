@@ -0,0 +1,19 @@
+package acd
+
+import "errors"
+
+// errXattrsUnsupported is returned by the extended attributes functions on
+// the platforms they are not implemented for.
+var errXattrsUnsupported = errors.New("extended attributes are not supported on this platform")
+
+// getXattrs returns the extended attributes of the file name, it is not
+// supported on darwin.
+func getXattrs(name string) (map[string][]byte, error) {
+	return nil, errXattrsUnsupported
+}
+
+// setXattr sets the extended attribute key of the file name to value, it is
+// not supported on darwin.
+func setXattr(name, key string, value []byte) error {
+	return errXattrsUnsupported
+}
@@ -0,0 +1,38 @@
+package acd
+
+import (
+	"bytes"
+	"syscall"
+)
+
+// getXattrs returns the extended attributes of the file name.
+func getXattrs(name string) (map[string][]byte, error) {
+	size, err := syscall.Listxattr(name, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = syscall.Listxattr(name, buf); err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string][]byte)
+	for _, key := range bytes.Split(bytes.TrimRight(buf[:size], "\x00"), []byte{0}) {
+		size, err := syscall.Getxattr(name, string(key), nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		if size, err = syscall.Getxattr(name, string(key), value); err != nil {
+			return nil, err
+		}
+		xattrs[string(key)] = value[:size]
+	}
+
+	return xattrs, nil
+}
+
+// setXattr sets the extended attribute key of the file name to value.
+func setXattr(name, key string, value []byte) error {
+	return syscall.Setxattr(name, key, value, 0)
+}
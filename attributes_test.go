@@ -0,0 +1,107 @@
+package acd
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestFileAttributes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acd-attributes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := path.Join(dir, "file")
+	if err := ioutil.WriteFile(name, []byte("content"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2015, 6, 1, 12, 30, 0, 500, time.UTC)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0640); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := fileAttributes(name, info, false)
+	if want, got := "2015-06-01T12:30:00.0000005Z", attrs[PropertyModTime]; want != got {
+		t.Errorf("fileAttributes mtime: want %s got %s", want, got)
+	}
+	if want, got := "640", attrs[PropertyMode]; want != got {
+		t.Errorf("fileAttributes mode: want %s got %s", want, got)
+	}
+	if attrs[PropertyUser] == "" || attrs[PropertyGroup] == "" {
+		t.Errorf("fileAttributes user and group: want names got %q and %q", attrs[PropertyUser], attrs[PropertyGroup])
+	}
+}
+
+func TestRestoreAttributes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acd-attributes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := path.Join(dir, "file")
+	if err := ioutil.WriteFile(name, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		c           = &Client{config: &Config{PropertiesOwner: "owner"}}
+		contentDate = time.Date(2014, 1, 2, 3, 4, 5, 0, time.UTC)
+		mtime       = time.Date(2015, 6, 1, 12, 30, 0, 0, time.UTC)
+	)
+	tests := []struct {
+		props map[string]string
+		mtime time.Time
+		mode  os.FileMode
+	}{
+		{map[string]string{PropertyModTime: mtime.Format(time.RFC3339Nano), PropertyMode: "600"}, mtime, 0600},
+		{map[string]string{PropertyMode: "640"}, contentDate, 0640},
+	}
+
+	for i, test := range tests {
+		n := &node.Node{
+			ContentProperties: node.ContentProperties{ContentDate: contentDate},
+			Properties:        node.Properties{"owner": test.props},
+		}
+		if err := c.restoreAttributes(fileTransfer{node: n, localPath: name}, &DownloadOptions{Preserve: true}); err != nil {
+			t.Fatalf("#%d: c.restoreAttributes() error: %s", i, err)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(test.mtime) {
+			t.Errorf("#%d: c.restoreAttributes() mtime: want %s got %s", i, test.mtime, info.ModTime())
+		}
+		if info.Mode().Perm() != test.mode {
+			t.Errorf("#%d: c.restoreAttributes() mode: want %s got %s", i, test.mode, info.Mode().Perm())
+		}
+	}
+}
+
+func TestUploadPreserveWithoutOwner(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+		config:   &Config{},
+	}
+	opts := &UploadOptions{Preserve: true}
+	if want, got := constants.ErrNoPropertiesOwner, c.UploadWithOptions("/file", strings.NewReader("content"), opts); want != got {
+		t.Errorf("c.UploadWithOptions() without an owner: want %v got %v", want, got)
+	}
+	if want, got := constants.ErrNoPropertiesOwner, c.UploadFolderWithOptions(os.TempDir(), "/", opts); want != got {
+		t.Errorf("c.UploadFolderWithOptions() without an owner: want %v got %v", want, got)
+	}
+}
@@ -0,0 +1,567 @@
+package acd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/acd.v0/crypt"
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+)
+
+// ChunkedSuffix is appended to the name of a file stored in chunks to name
+// its manifest. The chunks are named after the manifest followed by their
+// number, e.g. the file vm.img is stored as the manifest vm.img.acdchunked
+// and the chunks vm.img.acdchunked.0001, vm.img.acdchunked.0002 and so on.
+const ChunkedSuffix = ".acdchunked"
+
+var chunkRegexp = regexp.MustCompile(regexp.QuoteMeta(ChunkedSuffix) + `\.[0-9]+$`)
+
+type (
+	// chunkManifest describes a file stored in chunks.
+	chunkManifest struct {
+		// Size and MD5 are the size and the MD5 of the whole file.
+		Size int64  `json:"size"`
+		MD5  string `json:"md5"`
+
+		Chunks []chunkInfo `json:"chunks"`
+	}
+
+	// chunkInfo describes a chunk. Size is the size of its part of the file and
+	// MD5 the MD5 of its content as stored, i.e. encrypted if the file is.
+	chunkInfo struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+		MD5  string `json:"md5"`
+	}
+
+	// chunkedReader reads the chunks of a file one after the other and
+	// verifies the whole file once it was read.
+	chunkedReader struct {
+		c        *Client
+		path     string
+		manifest *chunkManifest
+		next     int
+		cur      io.ReadCloser
+		hash     hash.Hash
+		read     int64
+	}
+
+	// chunkedReaderAt reads a file stored in chunks from any offset, opening
+	// the chunks as they are needed.
+	chunkedReaderAt struct {
+		c        *Client
+		path     string
+		manifest *chunkManifest
+
+		mu    sync.Mutex
+		files map[int]File
+	}
+)
+
+// chunkPath returns the path of the chunk number i of the file p.
+func chunkPath(p string, i int) string {
+	return fmt.Sprintf("%s%s.%04d", p, ChunkedSuffix, i+1)
+}
+
+func isChunk(n *node.Node) bool {
+	return !n.IsDir() && chunkRegexp.MatchString(n.Name)
+}
+
+func isManifest(n *node.Node) bool {
+	return !n.IsDir() && strings.HasSuffix(n.Name, ChunkedSuffix)
+}
+
+// mergeChunks returns nodes with the chunks removed and the manifests of the
+// chunked files replaced by copies named after the file and of its size.
+func mergeChunks(nodes node.Nodes) node.Nodes {
+	sizes := make(map[string]int64)
+	for _, n := range nodes {
+		if isChunk(n) {
+			sizes[strings.ToLower(chunkRegexp.ReplaceAllString(n.Name, ChunkedSuffix))] += n.Size()
+		}
+	}
+
+	merged := make(node.Nodes, 0, len(nodes))
+	for _, n := range nodes {
+		switch {
+		case isChunk(n):
+		case isManifest(n):
+			ln := *n
+			ln.Name = strings.TrimSuffix(n.Name, ChunkedSuffix)
+			ln.ContentProperties.Size = uint64(sizes[strings.ToLower(n.Name)])
+			ln.ContentProperties.MD5 = ""
+			merged = append(merged, &ln)
+		default:
+			merged = append(merged, n)
+		}
+	}
+
+	return merged
+}
+
+// findNode returns the node of the plain path p, or the manifest of p if p is
+// stored in chunks.
+func (c *Client) findNode(p string) (n *node.Node, chunked bool, err error) {
+	if n, err = c.findSilently(p); err == nil {
+		return n, false, nil
+	}
+	if n, err = c.findSilently(p + ChunkedSuffix); err == nil {
+		return n, true, nil
+	}
+	log.Errorf("%s: %s", constants.ErrNodeNotFound, p)
+
+	return nil, false, constants.ErrNodeNotFound
+}
+
+// findSilently returns the node of the plain path p without logging an error
+// if it does not exist.
+func (c *Client) findSilently(p string) (*node.Node, error) {
+	n, found := c.NodeTree.Lookup(c.encryptPath(p))
+	if !found {
+		return nil, constants.ErrNodeNotFound
+	}
+
+	return n, nil
+}
+
+// chunkedNode returns a copy of the manifest of the chunked file p named
+// after p and of its size.
+func (c *Client) chunkedNode(p string, manifest *node.Node) *node.Node {
+	ln := *manifest
+	ln.Name = path.Base(p)
+	ln.ContentProperties.Size = 0
+	ln.ContentProperties.MD5 = ""
+	for i := 0; ; i++ {
+		n, err := c.findSilently(chunkPath(p, i))
+		if err != nil {
+			break
+		}
+		ln.ContentProperties.Size += uint64(c.plainSize(p, n))
+	}
+
+	return &ln
+}
+
+// plainSize returns the size of the content of the node n of the plain path p.
+func (c *Client) plainSize(p string, n *node.Node) int64 {
+	if !c.isEncrypted(p) {
+		return n.Size()
+	}
+	size, err := crypt.DecryptedSize(n.Size())
+	if err != nil {
+		return n.Size()
+	}
+
+	return size
+}
+
+// readManifest returns the manifest of the chunked file p.
+func (c *Client) readManifest(p string) (*chunkManifest, error) {
+	rc, err := c.Download(p + ChunkedSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var m chunkManifest
+	if err := json.NewDecoder(rc).Decode(&m); err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONDecoding, err)
+		return nil, constants.ErrJSONDecoding
+	}
+
+	return &m, nil
+}
+
+// openChunk returns the verified content of the chunk number i of the file
+// p described by m.
+func (c *Client) openChunk(p string, m *chunkManifest, i int) (io.ReadCloser, error) {
+	cp := chunkPath(p, i)
+	n, err := c.NodeTree.FindNode(c.encryptPath(cp))
+	if err != nil {
+		return nil, err
+	}
+	if want := m.Chunks[i].MD5; want != "" && want != n.ContentProperties.MD5 {
+		log.Errorf("%s: %s: want %s got %s", constants.ErrMD5Mismatch, cp, want, n.ContentProperties.MD5)
+		return nil, constants.ErrMD5Mismatch
+	}
+	body, err := n.Download()
+	if err != nil {
+		return nil, err
+	}
+	vr := n.Verify(body)
+	if !c.isEncrypted(cp) {
+		return vr, nil
+	}
+	r, err := c.cipher.NewDecryptingReader(vr)
+	if err != nil {
+		vr.Close()
+		return nil, err
+	}
+
+	return readCloser{Reader: r, Closer: vr}, nil
+}
+
+// downloadChunked returns a reader of the chunked file p.
+func (c *Client) downloadChunked(p string) (io.ReadCloser, error) {
+	m, err := c.readManifest(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chunkedReader{c: c, path: p, manifest: m, hash: md5.New()}, nil
+}
+
+// openChunked opens the chunked file p for random-access reads. The chunks
+// are not verified as they are not read entirely.
+func (c *Client) openChunked(p string) (File, error) {
+	m, err := c.readManifest(p)
+	if err != nil {
+		return nil, err
+	}
+	ra := &chunkedReaderAt{c: c, path: p, manifest: m, files: make(map[int]File)}
+
+	return sectionFile{SectionReader: io.NewSectionReader(ra, 0, m.Size), Closer: ra}, nil
+}
+
+// uploadChunked uploads the file ft, of content r, into folder as chunks of
+// opts.ChunkSize bytes then its manifest. The existing chunks are overwritten
+// and the ones left from a bigger version of the file are removed.
+func (c *Client) uploadChunked(folder *node.Node, ft fileTransfer, r io.ReaderAt, opts *UploadOptions, tracker *progressTracker) error {
+	var old *chunkManifest
+	if _, err := c.findSilently(ft.remotePath + ChunkedSuffix); err == nil {
+		if old, err = c.readManifest(ft.remotePath); err != nil {
+			return err
+		}
+	}
+
+	var (
+		chunkOpts = *opts
+		hash      = md5.New()
+		m         = &chunkManifest{Size: ft.size}
+	)
+	chunkOpts.Labels = nil
+	// a duplicate chunk, or manifest, would be resolved to a node named after
+	// another file.
+	chunkOpts.Deduplicate = DeduplicateNone
+	manifestOpts := chunkOpts
+	manifestOpts.Labels = opts.Labels
+	for off, i := int64(0), 0; off < ft.size; off, i = off+opts.ChunkSize, i+1 {
+		size := opts.ChunkSize
+		if ft.size-off < size {
+			size = ft.size - off
+		}
+		if _, err := io.Copy(hash, io.NewSectionReader(r, off, size)); err != nil {
+			log.Errorf("%s: %s", constants.ErrReadingFileContents, err)
+			return constants.ErrReadingFileContents
+		}
+		cp := chunkPath(ft.remotePath, i)
+		n, err := c.uploadChunk(folder, cp, tracker.readerFrom(ft, io.NewSectionReader(r, off, size), off), &chunkOpts)
+		if err != nil {
+			return err
+		}
+		m.Chunks = append(m.Chunks, chunkInfo{Name: path.Base(cp), Size: size, MD5: n.ContentProperties.MD5})
+	}
+	m.MD5 = hex.EncodeToString(hash.Sum(nil))
+
+	content, err := json.Marshal(m)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
+		return constants.ErrJSONEncoding
+	}
+	if _, err := c.uploadChunk(folder, ft.remotePath+ChunkedSuffix, bytes.NewReader(content), &manifestOpts); err != nil {
+		return err
+	}
+	if old != nil {
+		for i := len(m.Chunks); i < len(old.Chunks); i++ {
+			if n, err := c.findSilently(chunkPath(ft.remotePath, i)); err == nil {
+				if err := c.NodeTree.RemoveNode(n); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	tracker.done(ft)
+
+	return nil
+}
+
+// uploadChunk uploads r as the plain path p into folder, overwriting the
+// existing node.
+func (c *Client) uploadChunk(folder *node.Node, p string, r io.Reader, opts *UploadOptions) (*node.Node, error) {
+	content, err := c.encryptReader(p, r)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := c.findSilently(p)
+	if err != nil {
+		return c.uploadVerified(content, opts, create(folder, path.Base(c.encryptPath(p)), opts))
+	}
+	if _, err := c.uploadVerified(content, opts, overwrite(existing)); err != nil {
+		return nil, err
+	}
+
+	return existing, existing.AddLabels(opts.Labels...)
+}
+
+// removeChunked removes the chunks then the manifest of the chunked file p.
+func (c *Client) removeChunked(p string) error {
+	for i := 0; ; i++ {
+		n, err := c.findSilently(chunkPath(p, i))
+		if err != nil {
+			break
+		}
+		if err := c.NodeTree.RemoveNode(n); err != nil {
+			return err
+		}
+	}
+	n, err := c.findSilently(p + ChunkedSuffix)
+	if err != nil {
+		return nil
+	}
+
+	return c.NodeTree.RemoveNode(n)
+}
+
+// downloadChunkedFile downloads the chunks of the file ft next to
+// ft.localPath, resuming them like any file, then joins them into
+// ft.localPath.
+func (c *Client) downloadChunkedFile(ft fileTransfer, opts *DownloadOptions, tracker *progressTracker) error {
+	m, err := c.readManifest(ft.remotePath)
+	if err != nil {
+		return err
+	}
+
+	var (
+		offset int64
+		parts  = make([]string, len(m.Chunks))
+	)
+	for i, chunk := range m.Chunks {
+		cp := chunkPath(ft.remotePath, i)
+		n, err := c.NodeTree.FindNode(c.encryptPath(cp))
+		if err != nil {
+			return err
+		}
+		if chunk.MD5 != "" && chunk.MD5 != n.ContentProperties.MD5 {
+			log.Errorf("%s: %s: want %s got %s", constants.ErrMD5Mismatch, cp, chunk.MD5, n.ContentProperties.MD5)
+			return constants.ErrMD5Mismatch
+		}
+		parts[i] = chunkPath(ft.localPath, i)
+		var progress func(int64)
+		if tracker != nil {
+			base := offset
+			progress = func(written int64) { tracker.set(ft, base+written) }
+		}
+		if err := c.fetchFile(n, cp, parts[i], opts, progress); err != nil {
+			return err
+		}
+		offset += chunk.Size
+	}
+	if err := joinChunks(ft.localPath, parts, m, opts.SkipVerify); err != nil {
+		return err
+	}
+	tracker.done(ft)
+
+	return nil
+}
+
+// joinChunks concatenates the files parts into localPath, followed by
+// node.PartialSuffix until it is complete, verifies its size against m, and
+// its MD5 unless skipVerify is set, then removes the parts. localPath is left
+// untouched if the file does not match.
+func joinChunks(localPath string, parts []string, m *chunkManifest, skipVerify bool) error {
+	partialPath := localPath + node.PartialSuffix
+	dst, err := os.Create(partialPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return constants.ErrCreateFile
+	}
+	var (
+		hash = md5.New()
+		size int64
+	)
+	for _, part := range parts {
+		src, err := os.Open(part)
+		if err != nil {
+			dst.Close()
+			os.Remove(partialPath)
+			log.Errorf("%s: %s", constants.ErrOpenFile, err)
+			return constants.ErrOpenFile
+		}
+		n, err := io.Copy(io.MultiWriter(dst, hash), src)
+		src.Close()
+		size += n
+		if err != nil {
+			dst.Close()
+			os.Remove(partialPath)
+			log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
+			return constants.ErrWritingFileContents
+		}
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(partialPath)
+		log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
+		return constants.ErrWritingFileContents
+	}
+
+	if size != m.Size {
+		os.Remove(partialPath)
+		log.Errorf("%s: %s: want %d bytes got %d", constants.ErrSizeMismatch, localPath, m.Size, size)
+		return constants.ErrSizeMismatch
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !skipVerify && sum != m.MD5 {
+		os.Remove(partialPath)
+		log.Errorf("%s: %s: want %s got %s", constants.ErrMD5Mismatch, localPath, m.MD5, sum)
+		return constants.ErrMD5Mismatch
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		os.Remove(partialPath)
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return constants.ErrCreateFile
+	}
+	for _, part := range parts {
+		os.Remove(part)
+	}
+
+	return nil
+}
+
+// Read reads the chunks in order. Instead of io.EOF, it returns
+// constants.ErrSizeMismatch or constants.ErrMD5Mismatch if the whole file does
+// not match its manifest.
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	for {
+		if cr.cur == nil {
+			if cr.next == len(cr.manifest.Chunks) {
+				return 0, cr.verify()
+			}
+			rc, err := cr.c.openChunk(cr.path, cr.manifest, cr.next)
+			if err != nil {
+				return 0, err
+			}
+			cr.cur = rc
+			cr.next++
+		}
+
+		n, err := cr.cur.Read(p)
+		cr.hash.Write(p[:n])
+		cr.read += int64(n)
+		if err != io.EOF {
+			return n, err
+		}
+		cr.cur.Close()
+		cr.cur = nil
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close closes the chunk being read.
+func (cr *chunkedReader) Close() error {
+	if cr.cur == nil {
+		return nil
+	}
+
+	return cr.cur.Close()
+}
+
+func (cr *chunkedReader) verify() error {
+	if cr.read != cr.manifest.Size {
+		log.Errorf("%s: %s: want %d bytes got %d", constants.ErrSizeMismatch, cr.path, cr.manifest.Size, cr.read)
+		return constants.ErrSizeMismatch
+	}
+	if sum := hex.EncodeToString(cr.hash.Sum(nil)); sum != cr.manifest.MD5 {
+		log.Errorf("%s: %s: want %s got %s", constants.ErrMD5Mismatch, cr.path, cr.manifest.MD5, sum)
+		return constants.ErrMD5Mismatch
+	}
+
+	return io.EOF
+}
+
+// ReadAt reads len(p) bytes from offset off, from as many chunks as needed.
+func (ra *chunkedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, constants.ErrInvalidSeek
+	}
+	var (
+		read  int
+		start int64
+	)
+	for i, chunk := range ra.manifest.Chunks {
+		if read == len(p) {
+			break
+		}
+		end := start + chunk.Size
+		if off < end {
+			f, err := ra.file(i)
+			if err != nil {
+				return read, err
+			}
+			want := len(p) - read
+			if int64(want) > end-off {
+				want = int(end - off)
+			}
+			n, err := f.ReadAt(p[read:read+want], off-start)
+			read += n
+			off += int64(n)
+			if err != nil && err != io.EOF {
+				return read, err
+			}
+			if n < want {
+				log.Errorf("%s: %s: chunk %d is too short", constants.ErrSizeMismatch, ra.path, i+1)
+				return read, constants.ErrSizeMismatch
+			}
+		}
+		start = end
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+
+	return read, nil
+}
+
+// Close closes the chunks which were opened.
+func (ra *chunkedReaderAt) Close() error {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	var err error
+	for i, f := range ra.files {
+		if cerr := f.Close(); cerr != nil {
+			err = cerr
+		}
+		delete(ra.files, i)
+	}
+
+	return err
+}
+
+// file returns the chunk number i, opened once.
+func (ra *chunkedReaderAt) file(i int) (File, error) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if f, found := ra.files[i]; found {
+		return f, nil
+	}
+	f, err := ra.c.OpenFile(chunkPath(ra.path, i))
+	if err != nil {
+		return nil, err
+	}
+	ra.files[i] = f
+
+	return f, nil
+}
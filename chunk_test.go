@@ -0,0 +1,114 @@
+package acd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestMergeChunks(t *testing.T) {
+	file := func(name string, size uint64) *node.Node {
+		return &node.Node{Name: name, Kind: "FILE", ContentProperties: node.ContentProperties{Size: size, MD5: "md5"}}
+	}
+	nodes := node.Nodes{
+		file("README.md", 10),
+		file("vm.img"+ChunkedSuffix, 200),
+		file("vm.img"+ChunkedSuffix+".0001", 1024),
+		file("vm.img"+ChunkedSuffix+".0002", 512),
+		&node.Node{Name: "pictures", Kind: "FOLDER"},
+	}
+
+	var (
+		names []string
+		sizes []int64
+	)
+	for _, n := range mergeChunks(nodes) {
+		names = append(names, n.Name)
+		sizes = append(sizes, n.Size())
+	}
+	if want := []string{"README.md", "vm.img", "pictures"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("mergeChunks names: want %v got %v", want, names)
+	}
+	if want := []int64{10, 1536, 0}; !reflect.DeepEqual(want, sizes) {
+		t.Errorf("mergeChunks sizes: want %v got %v", want, sizes)
+	}
+	if nodes[1].Name != "vm.img"+ChunkedSuffix || nodes[1].Size() != 200 {
+		t.Errorf("mergeChunks modified the manifest node")
+	}
+}
+
+func TestJoinChunks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acd-chunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		content = []byte("hello chunked world")
+		sum     = md5.Sum(content)
+		m       = &chunkManifest{Size: int64(len(content)), MD5: hex.EncodeToString(sum[:])}
+		local   = path.Join(dir, "file")
+		parts   []string
+	)
+	write := func() {
+		parts = nil
+		for i, chunk := range [][]byte{content[:8], content[8:16], content[16:]} {
+			parts = append(parts, chunkPath(local, i))
+			if err := ioutil.WriteFile(parts[i], chunk, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	write()
+	if err := joinChunks(local, parts, m, false); err != nil {
+		t.Fatalf("joinChunks error: %s", err)
+	}
+	if got, _ := ioutil.ReadFile(local); string(got) != string(content) {
+		t.Errorf("joinChunks: want %q got %q", content, got)
+	}
+	if _, err := os.Stat(parts[0]); !os.IsNotExist(err) {
+		t.Errorf("joinChunks did not remove the chunks")
+	}
+
+	// a file which does not match leaves the previous one in place.
+	tests := []struct {
+		md5        string
+		size       int64
+		skipVerify bool
+		want       error
+	}{
+		{"corrupt", m.Size, false, constants.ErrMD5Mismatch},
+		{m.MD5, m.Size + 1, false, constants.ErrSizeMismatch},
+		{m.MD5, m.Size - 1, true, constants.ErrSizeMismatch},
+		{"corrupt", m.Size, true, nil},
+	}
+	for _, test := range tests {
+		if err := ioutil.WriteFile(local, []byte("previous"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		write()
+		bad := &chunkManifest{Size: test.size, MD5: test.md5}
+		if err := joinChunks(local, parts, bad, test.skipVerify); err != test.want {
+			t.Errorf("joinChunks(%+v, %t): want %v got %v", bad, test.skipVerify, test.want, err)
+		}
+		want := "previous"
+		if test.want == nil {
+			want = string(content)
+		}
+		if got, _ := ioutil.ReadFile(local); string(got) != want {
+			t.Errorf("joinChunks(%+v, %t): want %q got %q", bad, test.skipVerify, want, got)
+		}
+		if _, err := os.Stat(local + node.PartialSuffix); !os.IsNotExist(err) {
+			t.Errorf("joinChunks(%+v, %t) left %s", bad, test.skipVerify, local+node.PartialSuffix)
+		}
+	}
+}
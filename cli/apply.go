@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/acd.v0"
+	"gopkg.in/acd.v0/internal/log"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	applyCommand = cli.Command{
+		Name:        "apply",
+		Usage:       "apply a saved transfer plan",
+		Description: "apply runs the folder transfers of a plan saved by cp --save-plan. A plan is only applied if the remote files it relies on did not change since it was made",
+		Action:      applyAction,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "do not show the progress of the transfers",
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(applyCommand)
+}
+
+func applyAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		log.Fatalf("apply: exactly one plan file is required. Given: %v", c.Args())
+	}
+	f, err := os.Open(c.Args()[0])
+	if err != nil {
+		log.Fatalf("apply: %s", err)
+	}
+	var plans []*acd.Plan
+	err = json.NewDecoder(f).Decode(&plans)
+	f.Close()
+	if err != nil {
+		log.Fatalf("apply: invalid plan file %q: %s", c.Args()[0], err)
+	}
+
+	// check every plan before applying the first one.
+	for _, plan := range plans {
+		if err := acdClient.CheckPlan(plan); err != nil {
+			log.Fatalf("apply: %s: %s, plan it again", plan.LocalPath, err)
+		}
+	}
+	for _, plan := range plans {
+		progress, finish := newProgress(c)
+		if plan.UploadOptions != nil {
+			plan.UploadOptions.Progress = progress
+		}
+		if plan.DownloadOptions != nil {
+			plan.DownloadOptions.Progress = progress
+		}
+		err := acdClient.ApplyPlan(plan)
+		finish()
+		if err != nil {
+			cpPrintErrors(plan.LocalPath, err)
+		}
+	}
+}
+
+// planning returns whether the cp flags ask for the plans of the folder
+// transfers rather than running them.
+func planning(c *cli.Context) bool {
+	return c.Bool("dry-run") || c.String("save-plan") != ""
+}
+
+// finishPlans prints plans, as JSON if --json was given, and saves them to
+// the file given by --save-plan.
+func finishPlans(c *cli.Context, plans []*acd.Plan) {
+	if c.Bool("json") {
+		b, err := json.MarshalIndent(plans, "", "  ")
+		if err != nil {
+			log.Fatalf("cp: %s", err)
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, plan := range plans {
+			printPlan(plan)
+		}
+	}
+
+	if name := c.String("save-plan"); name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			log.Fatalf("cp: %s", err)
+		}
+		err = json.NewEncoder(f).Encode(plans)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatalf("cp: error saving the plan to %q: %s", name, err)
+		}
+	}
+}
+
+// printPlan prints plan as a diff of its target: + for what is created, ~
+// overwritten, = skipped, > renamed and ! failing.
+func printPlan(plan *acd.Plan) {
+	upload := plan.Direction == acd.PlanDirectionUpload
+	if upload {
+		fmt.Printf("upload %s to acd://%s\n", plan.LocalPath, plan.RemotePath)
+	} else {
+		fmt.Printf("download acd://%s to %s\n", plan.RemotePath, plan.LocalPath)
+	}
+	for _, folder := range plan.Folders {
+		fmt.Printf("+ %s/\n", folder)
+	}
+
+	var skipped, failed int
+	for _, pf := range plan.Files {
+		target := pf.LocalPath
+		if upload {
+			target = pf.RemotePath
+		}
+		switch pf.Action {
+		case acd.PlanCreate:
+			fmt.Printf("+ %s (%s)\n", target, humanSize(uint64(pf.Size)))
+		case acd.PlanOverwrite:
+			fmt.Printf("~ %s (%s)\n", target, humanSize(uint64(pf.Size)))
+		case acd.PlanSkip:
+			fmt.Printf("= %s\n", target)
+			skipped++
+		case acd.PlanRename:
+			fmt.Printf("> %s -> %s (%s)\n", target, pf.RenamedPath, humanSize(uint64(pf.Size)))
+		case acd.PlanFail:
+			fmt.Printf("! %s: %s\n", pf.RemotePath, pf.Error)
+			failed++
+		}
+	}
+
+	transferred := len(plan.Files) - skipped - failed
+	fmt.Printf("%d folders to create, %d files to transfer (%s), %d skipped (%s), %d failing\n",
+		len(plan.Folders), transferred, humanSize(uint64(plan.Bytes)), skipped, humanSize(uint64(plan.SkippedBytes)), failed)
+}
@@ -2,13 +2,14 @@ package cli
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"strings"
 
+	"gopkg.in/acd.v0"
 	"gopkg.in/acd.v0/internal/constants"
 	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
 
 	"github.com/codegangsta/cli"
 )
@@ -21,12 +22,83 @@ var (
 		Action:       cpAction,
 		BashComplete: cpBashComplete,
 		Before:       cpBefore,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  "recursive, R",
 				Usage: "cp recursively",
 			},
-		},
+			cli.StringSliceFlag{
+				Name:  "label, l",
+				Usage: "add this label to the uploaded files, can be repeated",
+				Value: &cli.StringSlice{},
+			},
+			cli.IntFlag{
+				Name:  "jobs, j",
+				Usage: "number of files transferred at the same time",
+				Value: 4,
+			},
+			cli.BoolFlag{
+				Name:  "no-progress",
+				Usage: "do not show the progress of the transfers",
+			},
+			cli.IntFlag{
+				Name:  "retries",
+				Usage: "upload a file again this many times if the uploaded content does not match",
+			},
+			cli.BoolFlag{
+				Name:  "no-verify",
+				Usage: "do not verify the MD5 of the downloaded files, only their size",
+			},
+			cli.BoolFlag{
+				Name:  "trash-corrupt",
+				Usage: "trash the uploaded files whose content does not match once the retries are exhausted",
+			},
+			cli.StringFlag{
+				Name:  "conflict",
+				Usage: "when a file exists: skip, overwrite, overwrite-if-newer, overwrite-if-different, rename or fail",
+			},
+			cli.StringFlag{
+				Name:  "folder-conflict",
+				Usage: "when a folder exists instead of a file: skip, overwrite, overwrite-if-newer, overwrite-if-different, rename or fail",
+			},
+			cli.StringFlag{
+				Name:  "dedupe",
+				Usage: "let the server deduplicate the uploaded files and skip, link or fail on a duplicate",
+			},
+			cli.StringFlag{
+				Name:  "chunk-size",
+				Usage: "upload the files larger than this size, e.g. 2G, in chunks of this size",
+			},
+			cli.BoolFlag{
+				Name:  "preserve, p",
+				Usage: "record the modification time, mode and owner of the uploaded files in their properties, restore them on download",
+			},
+			cli.BoolFlag{
+				Name:  "xattrs",
+				Usage: "with --preserve, record and restore the extended attributes as well",
+			},
+			cli.StringFlag{
+				Name:  "symlinks",
+				Value: "follow",
+				Usage: "what to do with the symbolic links of the uploaded folders: follow, skip, or store them as links; on download, store restores the stored links, skip skips them",
+			},
+			cli.BoolFlag{
+				Name:  "report-special",
+				Usage: "report the sockets, devices and named pipes of the uploaded folders as errors rather than skipping them",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run, n",
+				Usage: "print what the folder transfers would do instead of running them",
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the plan of --dry-run as JSON",
+			},
+			cli.StringFlag{
+				Name:  "save-plan",
+				Usage: "save the plan of the folder transfers to this file for the apply command, implies --dry-run",
+			},
+		}, filterFlags...),
 	}
 
 	action string
@@ -37,28 +109,81 @@ func init() {
 }
 
 func cpAction(c *cli.Context) {
-	if strings.HasPrefix(c.Args()[len(c.Args())-1], "acd://") {
-		cpUpload(c)
+	// the shell cannot expand the remote sources, expand them ourselves.
+	srcs, err := expandRemoteArgs(c.Args()[:len(c.Args())-1])
+	if err != nil {
+		log.Fatalf("cp: %s", err)
+	}
+	dest := c.Args()[len(c.Args())-1]
+	if strings.HasPrefix(dest, "acd://") {
+		cpUpload(c, srcs, dest)
 	} else {
-		cpDownload(c)
+		cpDownload(c, srcs, dest)
 	}
 }
 
-func cpUpload(c *cli.Context) {
+func cpUpload(c *cli.Context, srcs []string, dest string) {
 	// make sure the destination is a folder if it exists upstream and more than
 	// one file is scheduled to be copied.
-	dest := strings.TrimPrefix(c.Args()[len(c.Args())-1], "acd://")
-	destNode, err := acdClient.NodeTree.FindNode(dest)
+	dest = strings.TrimPrefix(dest, "acd://")
+	destNode, err := acdClient.FindNode(dest)
 	if err == nil {
 		// make sure if the remote node exists, it is a folder.
-		if len(c.Args()) > 2 {
+		if len(srcs) > 1 {
 			if !destNode.IsDir() {
 				log.Fatalf("cp: target %q is not a directory", dest)
 			}
 		}
 	}
 
-	for _, src := range c.Args()[:len(c.Args())-1] {
+	f, err := newFilter(c)
+	if err != nil {
+		log.Fatalf("cp: %s", err)
+	}
+	opts := &acd.UploadOptions{
+		Overwrite:     true,
+		Labels:        c.StringSlice("label"),
+		Retries:       c.Int("retries"),
+		TrashCorrupt:  c.Bool("trash-corrupt"),
+		Filter:        f,
+		Preserve:      c.Bool("preserve"),
+		Xattrs:        c.Bool("xattrs"),
+		ReportSpecial: c.Bool("report-special"),
+	}
+	for flag, policy := range map[string]*acd.ConflictPolicy{
+		"conflict":        &opts.Conflict,
+		"folder-conflict": &opts.FolderConflict,
+	} {
+		if s := c.String(flag); s != "" {
+			p, err := parseConflictPolicy(s)
+			if err != nil {
+				log.Fatalf("cp: --%s: %s", flag, err)
+			}
+			*policy = p
+		}
+	}
+	if s := c.String("dedupe"); s != "" {
+		policies := map[string]acd.DeduplicatePolicy{
+			"skip": acd.DeduplicateSkip,
+			"link": acd.DeduplicateLink,
+			"fail": acd.DeduplicateFail,
+		}
+		policy, found := policies[s]
+		if !found {
+			log.Fatalf("cp: invalid --dedupe %q, want skip, link or fail", s)
+		}
+		opts.Deduplicate = policy
+	}
+	opts.Symlinks = parseSymlinkPolicy(c)
+	if s := c.String("chunk-size"); s != "" {
+		size, err := node.ParseSize(s)
+		if err != nil {
+			log.Fatalf("cp: %s", err)
+		}
+		opts.ChunkSize = size
+	}
+	var plans []*acd.Plan
+	for _, src := range srcs {
 		if strings.HasPrefix(src, "acd://") {
 			fmt.Printf("cp: target %q is amazon, src cannot be amazon when destination is amazon. Skipping\n", src)
 			continue
@@ -83,35 +208,71 @@ func cpUpload(c *cli.Context) {
 				}
 				destFile = fmt.Sprintf("%s/%s", dest, path.Base(src))
 			}
-			acdClient.UploadFolder(src, destFile, true, true)
+			folderOpts := *opts
+			folderOpts.Recursive = true
+			folderOpts.Jobs = c.Int("jobs")
+			if planning(c) {
+				plan, err := acdClient.PlanUpload(src, destFile, &folderOpts)
+				if err != nil {
+					log.Fatalf("cp: error planning %q: %s", src, err)
+				}
+				plans = append(plans, plan)
+				continue
+			}
+			progress, finish := newProgress(c)
+			folderOpts.Progress = progress
+			err := acdClient.UploadFolderWithOptions(src, destFile, &folderOpts)
+			finish()
+			if err != nil {
+				cpPrintErrors(src, err)
+			}
+			continue
+		}
+		if !stat.Mode().IsRegular() {
+			fmt.Printf("cp: %q is not a regular file. Skipping\n", src)
+			continue
+		}
+		if planning(c) {
+			fmt.Printf("cp: %q is not a directory, only folder transfers are planned. Skipping\n", src)
 			continue
 		}
 		f, err := os.Open(src)
 		if err != nil {
 			log.Fatalf("%s: %s -- %s", constants.ErrOpenFile, err, src)
 		}
-		err = acdClient.Upload(dest, true, f)
+		progress, finish := newProgress(c)
+		opts.Progress = progress
+		err = acdClient.UploadWithOptions(dest, f, opts)
+		finish()
 		f.Close()
 		if err != nil {
 			log.Fatalf("%s: %s", err, dest)
 		}
 	}
+	if planning(c) {
+		finishPlans(c, plans)
+	}
 }
 
-func cpDownload(c *cli.Context) {
-	dest := c.Args()[len(c.Args())-1]
+func cpDownload(c *cli.Context, srcs []string, dest string) {
 	destDir := false
 	destStat, err := os.Stat(dest)
 	if err == nil && destStat.IsDir() {
 		destDir = true
 	}
-	if len(c.Args()) > 2 {
+	if len(srcs) > 1 {
 		if err == nil && !destDir {
 			log.Fatalf("cp: target %q is not a directory", dest)
 		}
 	}
 
-	for _, src := range c.Args()[:len(c.Args())-1] {
+	f, err := newFilter(c)
+	if err != nil {
+		log.Fatalf("cp: %s", err)
+	}
+	symlinks := parseSymlinkPolicy(c)
+	var plans []*acd.Plan
+	for _, src := range srcs {
 		if !strings.HasPrefix(src, "acd://") {
 			fmt.Printf("cp: source %q is local, src cannot be local when destination is local. Skipping\n", src)
 			continue
@@ -121,33 +282,77 @@ func cpDownload(c *cli.Context) {
 		if destDir {
 			destPath = path.Join(destPath, path.Base(srcPath))
 		}
-		srcNode, err := acdClient.GetNodeTree().FindNode(srcPath)
+		srcNode, err := acdClient.FindNode(srcPath)
 		if err != nil {
 			fmt.Printf("cp: source %q not found. Skipping", src)
 			continue
 		}
+		if planning(c) {
+			if !srcNode.IsDir() {
+				fmt.Printf("cp: %q is not a directory, only folder transfers are planned. Skipping\n", src)
+				continue
+			}
+			plan, err := acdClient.PlanDownload(destPath, srcPath, &acd.DownloadOptions{
+				Recursive:  c.Bool("recursive"),
+				Jobs:       c.Int("jobs"),
+				SkipVerify: c.Bool("no-verify"),
+				Filter:     f,
+				Preserve:   c.Bool("preserve"),
+				Xattrs:     c.Bool("xattrs"),
+				Symlinks:   symlinks,
+			})
+			if err != nil {
+				log.Fatalf("cp: error planning %q: %s", src, err)
+			}
+			plans = append(plans, plan)
+			continue
+		}
+		progress, finish := newProgress(c)
+		opts := &acd.DownloadOptions{
+			Recursive:  c.Bool("recursive"),
+			Jobs:       c.Int("jobs"),
+			Progress:   progress,
+			SkipVerify: c.Bool("no-verify"),
+			Filter:     f,
+			Preserve:   c.Bool("preserve"),
+			Xattrs:     c.Bool("xattrs"),
+			Symlinks:   symlinks,
+		}
 		if srcNode.IsDir() {
-			acdClient.DownloadFolder(destPath, srcPath, c.Bool("recursive"))
-		} else {
-			content, err := acdClient.Download(srcPath)
+			err := acdClient.DownloadFolderWithOptions(destPath, srcPath, opts)
+			finish()
 			if err != nil {
-				fmt.Printf("cp: error downloading source %q. Skipping", src)
+				cpPrintErrors(src, err)
 			}
-			// TODO: respect umask
-			if err := os.MkdirAll(path.Dir(destPath), os.FileMode(0755)); err != nil {
+		} else {
+			// the umask applies to the mode of the folders.
+			if err := os.MkdirAll(path.Dir(destPath), os.FileMode(0777)); err != nil {
 				fmt.Printf("cp: error creating the parents folders of %q: %s. Skipping", destPath, err)
 				continue
 			}
-			// TODO: respect umask
-			f, err := os.Create(destPath)
+			err := acdClient.DownloadToFile(destPath, srcPath, opts)
+			finish()
 			if err != nil {
-				fmt.Printf("cp: error writing %q: %s. Skipping", destPath, err)
-				continue
+				fmt.Printf("cp: error downloading source %q: %s. Skipping", src, err)
 			}
-			io.Copy(f, content)
-			f.Close()
 		}
 	}
+	if planning(c) {
+		finishPlans(c, plans)
+	}
+}
+
+// cpPrintErrors prints the error of each file of the folder src which failed
+// to be copied.
+func cpPrintErrors(src string, err error) {
+	errs, ok := err.(acd.TransferErrors)
+	if !ok {
+		fmt.Printf("cp: error copying %q: %s\n", src, err)
+		return
+	}
+	for _, e := range errs {
+		fmt.Printf("cp: error copying %q: %s\n", e.Path, e.Err)
+	}
 }
 
 func cpBashComplete(c *cli.Context) {
@@ -168,3 +373,36 @@ func cpBefore(c *cli.Context) error {
 
 	return nil
 }
+
+// parseConflictPolicy parses the name of an acd.ConflictPolicy.
+func parseConflictPolicy(s string) (acd.ConflictPolicy, error) {
+	policies := map[string]acd.ConflictPolicy{
+		"skip":                   acd.ConflictSkip,
+		"overwrite":              acd.ConflictOverwrite,
+		"overwrite-if-newer":     acd.ConflictOverwriteIfNewer,
+		"overwrite-if-different": acd.ConflictOverwriteIfDifferent,
+		"rename":                 acd.ConflictRename,
+		"fail":                   acd.ConflictFail,
+	}
+	policy, found := policies[s]
+	if !found {
+		return 0, fmt.Errorf("invalid conflict policy %q", s)
+	}
+
+	return policy, nil
+}
+
+// parseSymlinkPolicy returns the acd.SymlinkPolicy named by --symlinks.
+func parseSymlinkPolicy(c *cli.Context) acd.SymlinkPolicy {
+	policies := map[string]acd.SymlinkPolicy{
+		"follow": acd.SymlinkFollow,
+		"skip":   acd.SymlinkSkip,
+		"store":  acd.SymlinkStore,
+	}
+	policy, found := policies[c.String("symlinks")]
+	if !found {
+		log.Fatalf("cp: invalid --symlinks %q, want follow, skip or store", c.String("symlinks"))
+	}
+
+	return policy
+}
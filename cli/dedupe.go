@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	dedupeCommand = cli.Command{
+		Name:         "dedupe",
+		Usage:        "find and remove duplicate files",
+		Description:  "dedupe groups the files under the acd:// path (the root by default) by MD5 and size, and reports the space that could be reclaimed. When --keep or --prefer is given, one copy is kept and the others are trashed, or replaced by the kept copy with --link",
+		Action:       dedupeAction,
+		BashComplete: dedupeBashComplete,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "keep, k",
+				Usage: "keep one copy and trash the others: oldest or shortest (path)",
+			},
+			cli.StringFlag{
+				Name:  "prefer, p",
+				Usage: "keep the copy under this acd:// folder, falling back to --keep (oldest by default)",
+			},
+			cli.BoolFlag{
+				Name:  "link",
+				Usage: "add the kept copy to the folders of the trashed copies",
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(dedupeCommand)
+}
+
+func dedupeAction(c *cli.Context) {
+	root := "/"
+	if len(c.Args()) > 0 {
+		if !strings.HasPrefix(c.Args()[0], "acd://") {
+			log.Fatalf("dedupe: the path must be prefixed by acd://. Given: %v", c.Args())
+		}
+		root = strings.TrimPrefix(c.Args()[0], "acd://")
+	}
+
+	var keep node.KeepRule
+	switch c.String("keep") {
+	case "":
+		if c.String("prefer") != "" {
+			keep = node.KeepOldest
+		}
+	case "oldest":
+		keep = node.KeepOldest
+	case "shortest":
+		keep = node.KeepShortestPath
+	default:
+		log.Fatalf("dedupe: unknown keep rule %q", c.String("keep"))
+	}
+	if prefer := c.String("prefer"); prefer != "" {
+		keep = node.KeepInFolder(strings.TrimPrefix(prefer, "acd://"), keep)
+	}
+
+	dups, err := acdClient.GetNodeTree().FindDuplicates(root)
+	if err != nil {
+		log.Fatalf("dedupe: %s", err)
+	}
+
+	var reclaimable uint64
+	for _, d := range dups {
+		reclaimable += d.Reclaimable()
+		fmt.Printf("%s %s, %d copies\n", d.MD5, humanSize(d.Size), len(d.Matches))
+		if keep == nil {
+			for _, m := range d.Matches {
+				fmt.Printf("\t%s\n", m.Path)
+			}
+			continue
+		}
+
+		kept, trashed, err := acdClient.GetNodeTree().Dedupe(d, keep, c.Bool("link"))
+		fmt.Printf("\t%s (kept)\n", kept.Path)
+		for _, m := range trashed {
+			fmt.Printf("\t%s (trashed)\n", m.Path)
+		}
+		if err != nil {
+			log.Fatalf("dedupe: %s", err)
+		}
+	}
+
+	verb := "can be"
+	if keep != nil {
+		verb = "were"
+	}
+	fmt.Printf("%s %s reclaimed in %d sets of duplicates\n", humanSize(reclaimable), verb, len(dups))
+}
+
+func dedupeBashComplete(c *cli.Context) {
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"time"
+
+	"gopkg.in/acd.v0/filter"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+// filterFlags are the flags of the commands transferring folders, see
+// newFilter.
+var filterFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "include",
+		Usage: "only transfer the files matching this gitignore-style pattern, can be repeated",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude",
+		Usage: "do not transfer the files and folders matching this gitignore-style pattern, can be repeated",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringFlag{
+		Name:  "exclude-from",
+		Usage: "read the exclude patterns from this file, in gitignore syntax",
+	},
+	cli.BoolFlag{
+		Name:  "no-ignore-file",
+		Usage: "do not read the " + filter.DefaultIgnoreFile + " files of the transferred folders",
+	},
+	cli.StringFlag{
+		Name:  "min-size",
+		Usage: "do not transfer the files smaller than this size, e.g. 10K",
+	},
+	cli.StringFlag{
+		Name:  "max-size",
+		Usage: "do not transfer the files larger than this size, e.g. 1G",
+	},
+	cli.StringFlag{
+		Name:  "min-age",
+		Usage: "do not transfer the files modified more recently than this age, e.g. 12h or 2d",
+	},
+	cli.StringFlag{
+		Name:  "max-age",
+		Usage: "do not transfer the files modified before this age, e.g. 30d or 2w",
+	},
+}
+
+// newFilter returns the filter.Filter of the filterFlags of c.
+func newFilter(c *cli.Context) (*filter.Filter, error) {
+	f := &filter.Filter{}
+	if !c.Bool("no-ignore-file") {
+		f.IgnoreFile = filter.DefaultIgnoreFile
+	}
+	for _, pattern := range c.StringSlice("include") {
+		if err := f.Include(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if name := c.String("exclude-from"); name != "" {
+		if err := f.ReadRulesFile(name); err != nil {
+			return nil, err
+		}
+	}
+	for _, pattern := range c.StringSlice("exclude") {
+		if err := f.Exclude(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	for flag, size := range map[string]*int64{"min-size": &f.MinSize, "max-size": &f.MaxSize} {
+		if s := c.String(flag); s != "" {
+			if *size, err = node.ParseSize(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for flag, age := range map[string]*time.Duration{"min-age": &f.MinAge, "max-age": &f.MaxAge} {
+		if s := c.String(flag); s != "" {
+			if *age, err = parseAge(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return f, nil
+}
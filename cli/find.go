@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	findCommand = cli.Command{
+		Name:            "find",
+		Usage:           "search for nodes matching an expression",
+		Description:     "find walks the acd:// paths and prints the nodes matching the expression, like find(1). The tests are -name, -path, -type, -size, -mtime, -modified-before, -modified-after, -content-before, -content-after, -content-type, -extension, -md5, -label and -status combined with (, ), !, -a and -o. The actions are -print (the default), -print0, -delete and -exec command {} ; and --json prints one JSON object per node",
+		Action:          findAction,
+		BashComplete:    findBashComplete,
+		SkipFlagParsing: true,
+	}
+)
+
+type findOptions struct {
+	roots  []string
+	expr   []string
+	json   bool
+	print0 bool
+	delete bool
+	exec   []string
+}
+
+func init() {
+	registerCommand(findCommand)
+}
+
+func findAction(c *cli.Context) {
+	opts, err := parseFindArgs(c.Args())
+	if err != nil {
+		log.Fatalf("find: %s", err)
+	}
+	p, err := node.ParseQuery(opts.expr)
+	if err != nil {
+		log.Fatalf("find: %s", err)
+	}
+
+	var matches []node.Match
+	for _, root := range opts.roots {
+		m, err := acdClient.Find(strings.TrimPrefix(root, "acd://"), p)
+		if err != nil {
+			log.Fatalf("find: %s: %s", root, err)
+		}
+		matches = append(matches, m...)
+	}
+
+	switch {
+	case opts.delete:
+		// delete the children before their parents, like find -depth.
+		deleted := make(map[string]bool)
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i].Node.Root || deleted[matches[i].Node.ID] {
+				continue
+			}
+			deleted[matches[i].Node.ID] = true
+			// the node of an encrypted path is a decrypted copy.
+			n, err := findNode(matches[i].Path)
+			if err == nil {
+				err = acdClient.GetNodeTree().RemoveNode(n)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "find: cannot delete %q: %s\n", matches[i].Path, err)
+			}
+		}
+	case len(opts.exec) > 0:
+		for _, m := range matches {
+			findExec(opts.exec, m.Path)
+		}
+	case opts.json:
+		enc := json.NewEncoder(os.Stdout)
+		for _, m := range matches {
+			// do not dump the entire sub-tree of folders.
+			n := *m.Node
+			n.Nodes = nil
+			enc.Encode(node.Match{Path: m.Path, Node: &n})
+		}
+	case opts.print0:
+		for _, m := range matches {
+			fmt.Printf("%s\x00", m.Path)
+		}
+	default:
+		for _, m := range matches {
+			fmt.Println(m.Path)
+		}
+	}
+}
+
+// parseFindArgs splits the arguments in the roots, the expression and the
+// actions. The actions apply to every matching node wherever they appear.
+func parseFindArgs(args []string) (*findOptions, error) {
+	var (
+		err  error
+		opts = &findOptions{}
+	)
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--json":
+			opts.json = true
+		case strings.HasPrefix(arg, "acd://") && len(opts.expr) == 0:
+			opts.roots = append(opts.roots, arg)
+		case arg == "-print":
+		case arg == "-print0":
+			opts.print0 = true
+		case arg == "-delete":
+			opts.delete = true
+		case arg == "-exec":
+			for i++; i < len(args) && args[i] != ";"; i++ {
+				opts.exec = append(opts.exec, args[i])
+			}
+			if i == len(args) || len(opts.exec) == 0 {
+				return nil, fmt.Errorf("-exec requires a command terminated by ;")
+			}
+		default:
+			opts.expr = append(opts.expr, arg)
+		}
+	}
+	if len(opts.roots) == 0 {
+		return nil, fmt.Errorf("at least one path prefixed by acd:// is required. Given: %v", args)
+	}
+	if opts.roots, err = expandRemoteArgs(opts.roots); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+// findExec runs the command replacing every {} by the acd:// path.
+func findExec(command []string, path string) {
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = strings.Replace(arg, "{}", "acd://"+path, -1)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "find: %s: %s\n", strings.Join(args, " "), err)
+	}
+}
+
+func findBashComplete(c *cli.Context) {
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"strings"
+
+	"gopkg.in/acd.v0/node"
+)
+
+// expandRemoteArgs expands the glob patterns of the arguments prefixed by
+// acd:// against the node tree, decrypted under the encrypted folder, the
+// shell cannot do it for us. Arguments which are local, have no magic
+// characters or do not match anything are returned as-is so the command can
+// report them.
+func expandRemoteArgs(args []string) ([]string, error) {
+	var expanded []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "acd://") || !node.HasMeta(arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		matches, err := acdClient.Glob(strings.TrimPrefix(arg, "acd://"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			expanded = append(expanded, arg)
+			continue
+		}
+		for _, match := range matches {
+			expanded = append(expanded, "acd://"+match)
+		}
+	}
+
+	return expanded, nil
+}
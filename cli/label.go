@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	labelCommand = cli.Command{
+		Name:        "label",
+		Usage:       "manage the labels of nodes",
+		Description: "label adds and removes labels on nodes and lists the nodes carrying a label. All paths must be prefixed by acd://",
+		Subcommands: []cli.Command{
+			{
+				Name:   "add",
+				Usage:  "add a label to nodes: label add LABEL acd://path...",
+				Action: labelAddAction,
+			},
+			{
+				Name:   "remove",
+				Usage:  "remove a label from nodes: label remove LABEL acd://path...",
+				Action: labelRemoveAction,
+			},
+			{
+				Name:   "list",
+				Usage:  "list the paths of the nodes carrying a label: label list LABEL",
+				Action: labelListAction,
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(labelCommand)
+}
+
+func labelAddAction(c *cli.Context) {
+	label, nodes := labelArgs(c)
+	for path, n := range nodes {
+		if err := n.AddLabels(label); err != nil {
+			fmt.Printf("label: cannot label %q: %s\n", path, err)
+		}
+	}
+}
+
+func labelRemoveAction(c *cli.Context) {
+	label, nodes := labelArgs(c)
+	for path, n := range nodes {
+		if err := n.RemoveLabels(label); err != nil {
+			fmt.Printf("label: cannot unlabel %q: %s\n", path, err)
+		}
+	}
+}
+
+func labelListAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		log.Fatalf("label: exactly one label is required. Given: %v", c.Args())
+	}
+	for _, n := range acdClient.GetNodeTree().FindByLabel(c.Args()[0]) {
+		p, err := acdClient.PathOf(n)
+		if err != nil {
+			p = "?/" + n.Name
+		}
+		fmt.Println(p)
+	}
+}
+
+// labelArgs returns the label and the nodes, by path, given as arguments.
+func labelArgs(c *cli.Context) (string, map[string]*node.Node) {
+	if len(c.Args()) < 2 {
+		log.Fatalf("label: a label and at least one acd:// path are required. Given: %v", c.Args())
+	}
+	for _, arg := range c.Args()[1:] {
+		if !strings.HasPrefix(arg, "acd://") {
+			log.Fatalf("label: %q is not prefixed by acd://", arg)
+		}
+	}
+	args, err := expandRemoteArgs(c.Args()[1:])
+	if err != nil {
+		log.Fatalf("label: %s", err)
+	}
+
+	nodes := make(map[string]*node.Node, len(args))
+	for _, arg := range args {
+		n, err := findNode(strings.TrimPrefix(arg, "acd://"))
+		if err != nil {
+			fmt.Printf("label: %q not found. Skipping\n", arg)
+			continue
+		}
+		nodes[arg] = n
+	}
+
+	return c.Args()[0], nodes
+}
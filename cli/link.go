@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	linkCommand = cli.Command{
+		Name:         "link",
+		Usage:        "print temporary download links",
+		Description:  "link prints a temporary link to download each of the given files without authentication, along with its expiry. All paths must be prefixed by acd://",
+		Action:       linkAction,
+		BashComplete: linkBashComplete,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "print one JSON object per link",
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(linkCommand)
+}
+
+func linkAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		log.Fatalf("link: at least one acd:// path is required")
+	}
+	for _, arg := range c.Args() {
+		if !strings.HasPrefix(arg, "acd://") {
+			log.Fatalf("link: %q is not prefixed by acd://", arg)
+		}
+	}
+	args, err := expandRemoteArgs(c.Args())
+	if err != nil {
+		log.Fatalf("link: %s", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, arg := range args {
+		p := strings.TrimPrefix(arg, "acd://")
+		n, err := findNode(p)
+		if err != nil {
+			fmt.Printf("link: %q not found. Skipping\n", arg)
+			continue
+		}
+		if acdClient.IsEncrypted(p) {
+			fmt.Printf("link: %q is encrypted, its link would serve the encrypted content. Skipping\n", arg)
+			continue
+		}
+		link, err := n.TempLink()
+		if err != nil {
+			fmt.Printf("link: cannot link %q: %s\n", arg, err)
+			continue
+		}
+
+		if c.Bool("json") {
+			enc.Encode(struct {
+				Path string `json:"path"`
+				*node.TempLink
+			}{arg, link})
+			continue
+		}
+		fmt.Printf("%s\t%s\t%s\n", arg, link.Expires, link.URL)
+	}
+}
+
+func linkBashComplete(c *cli.Context) {
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	lnCommand = cli.Command{
+		Name:         "ln",
+		Usage:        "link nodes into other folders",
+		Description:  "ln adds the destination folder as an extra parent of the sources, the nodes then live in several folders without being copied. Multiple sources can be given and the last entry is the destination folder. With --unlink, the given nodes are removed from the folder they are listed in, as long as they remain in another folder. All paths must be prefixed by acd://",
+		Action:       lnAction,
+		BashComplete: lnBashComplete,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "unlink, u",
+				Usage: "remove the nodes from the folder of the given paths",
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(lnCommand)
+}
+
+func lnAction(c *cli.Context) {
+	for _, arg := range c.Args() {
+		if !strings.HasPrefix(arg, "acd://") {
+			log.Fatalf("ln: %q is not prefixed by acd://", arg)
+		}
+	}
+	if c.Bool("unlink") {
+		lnUnlink(c)
+		return
+	}
+	if len(c.Args()) < 2 {
+		log.Fatalf("ln: a source and a destination folder are required. Given: %v", c.Args())
+	}
+
+	srcs, err := expandRemoteArgs(c.Args()[:len(c.Args())-1])
+	if err != nil {
+		log.Fatalf("ln: %s", err)
+	}
+	dest := strings.TrimPrefix(c.Args()[len(c.Args())-1], "acd://")
+	destNode, err := findNode(dest)
+	if err != nil || !destNode.IsDir() {
+		log.Fatalf("ln: target %q is not a directory", dest)
+	}
+
+	for _, src := range srcs {
+		srcPath := strings.TrimPrefix(src, "acd://")
+		srcNode, err := findNode(srcPath)
+		if err != nil {
+			fmt.Printf("ln: source %q not found. Skipping\n", src)
+			continue
+		}
+		// the name of the node is encrypted or not, it cannot be both.
+		if acdClient.IsEncrypted(srcPath) != acdClient.IsEncrypted(path.Join(dest, path.Base(srcPath))) {
+			fmt.Printf("ln: cannot link %q into %q: only one of them is encrypted\n", src, dest)
+			continue
+		}
+		if err := srcNode.AddParent(destNode); err != nil {
+			fmt.Printf("ln: cannot link %q into %q: %s\n", src, dest, err)
+		}
+	}
+}
+
+func lnUnlink(c *cli.Context) {
+	args, err := expandRemoteArgs(c.Args())
+	if err != nil {
+		log.Fatalf("ln: %s", err)
+	}
+	for _, arg := range args {
+		p := strings.TrimPrefix(arg, "acd://")
+		n, err := findNode(p)
+		if err != nil {
+			fmt.Printf("ln: %q not found. Skipping\n", arg)
+			continue
+		}
+		parent, err := findNode(path.Dir("/" + strings.Trim(p, "/")))
+		if err != nil {
+			fmt.Printf("ln: the folder of %q not found. Skipping\n", arg)
+			continue
+		}
+		if err := n.RemoveParent(parent); err != nil {
+			fmt.Printf("ln: cannot unlink %q: %s\n", arg, err)
+		}
+	}
+}
+
+func lnBashComplete(c *cli.Context) {
+}
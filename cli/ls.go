@@ -34,7 +34,31 @@ func init() {
 }
 
 func lsAction(c *cli.Context) {
+	var (
+		files node.Nodes
+		dirs  []string
+	)
+
+	// list the files first, then the contents of each folder like ls(1).
 	for _, p := range paths {
+		n, err := acdClient.FindNode(p)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n.IsDir() {
+			dirs = append(dirs, p)
+		} else {
+			files = append(files, n)
+		}
+	}
+	if len(files) > 0 {
+		lsNodes(c, files)
+		if len(dirs) > 0 {
+			fmt.Println()
+		}
+	}
+
+	for _, p := range dirs {
 		nodes, err := acdClient.List(p)
 		if err != nil {
 			log.Fatal(err)
@@ -44,11 +68,7 @@ func lsAction(c *cli.Context) {
 			fmt.Printf("%s:\n", p)
 		}
 
-		if c.Bool("long") {
-			lsLong(nodes)
-		} else {
-			lsShort(nodes)
-		}
+		lsNodes(c, nodes)
 
 		if len(paths) > 1 {
 			fmt.Println()
@@ -57,7 +77,11 @@ func lsAction(c *cli.Context) {
 }
 
 func lsBefore(c *cli.Context) error {
-	for _, arg := range c.Args() {
+	args, err := expandRemoteArgs(c.Args())
+	if err != nil {
+		return fmt.Errorf("ls: %s", err)
+	}
+	for _, arg := range args {
 		if !strings.HasPrefix(arg, "acd://") {
 			continue
 		}
@@ -74,6 +98,14 @@ func lsBefore(c *cli.Context) error {
 func lsBashComplete(c *cli.Context) {
 }
 
+func lsNodes(c *cli.Context, nodes node.Nodes) {
+	if c.Bool("long") {
+		lsLong(nodes)
+	} else {
+		lsShort(nodes)
+	}
+}
+
 func lsLong(nodes node.Nodes) {
 	for _, n := range nodes {
 		if n.IsDir() {
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	mvCommand = cli.Command{
+		Name:         "mv",
+		Usage:        "move and rename files",
+		Description:  "mv moves or renames nodes on the server without downloading them, multiple sources can be given. It follows the usage of mv whereas the last entry is the destination and has to be a folder if multiple sources were given. All paths must be prefixed by acd://",
+		Action:       mvAction,
+		BashComplete: mvBashComplete,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "no-clobber, n",
+				Usage: "do not overwrite an existing file",
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(mvCommand)
+}
+
+func mvAction(c *cli.Context) {
+	if len(c.Args()) < 2 {
+		log.Fatalf("mv: a source and a destination are required. Given: %v", c.Args())
+	}
+	for _, arg := range c.Args() {
+		if !strings.HasPrefix(arg, "acd://") {
+			log.Fatalf("mv: %q is not prefixed by acd://", arg)
+		}
+	}
+	srcs, err := expandRemoteArgs(c.Args()[:len(c.Args())-1])
+	if err != nil {
+		log.Fatalf("mv: %s", err)
+	}
+	dest := "/" + strings.Trim(strings.TrimPrefix(c.Args()[len(c.Args())-1], "acd://"), "/")
+	destNode, _ := findNode(dest)
+	destDir := destNode != nil && destNode.IsDir()
+	if len(srcs) > 1 && !destDir {
+		log.Fatalf("mv: target %q is not a directory", dest)
+	}
+
+	for _, src := range srcs {
+		srcPath := "/" + strings.Trim(strings.TrimPrefix(src, "acd://"), "/")
+		toPath, name := path.Dir(dest), path.Base(dest)
+		if destDir {
+			toPath, name = dest, path.Base(srcPath)
+		}
+		if err := mv(srcPath, toPath, name, c.Bool("no-clobber")); err != nil {
+			fmt.Printf("mv: cannot move %q to %q: %s\n", srcPath, path.Join(toPath, name), err)
+		}
+	}
+}
+
+// mv moves the node at srcPath to the folder toPath under the given name,
+// overwriting an existing file unless noClobber is true.
+func mv(srcPath, toPath, name string, noClobber bool) error {
+	srcNode, err := findNode(srcPath)
+	if err != nil {
+		return err
+	}
+	if srcNode.Root {
+		return fmt.Errorf("cannot move the root folder")
+	}
+	fromNode, err := findNode(path.Dir(srcPath))
+	if err != nil {
+		return err
+	}
+	toNode, err := findNode(toPath)
+	if err != nil {
+		return err
+	}
+	if !toNode.IsDir() {
+		return fmt.Errorf("%q is not a directory", toPath)
+	}
+	// the name of the node is encrypted or not, it cannot be both.
+	if acdClient.IsEncrypted(srcPath) != acdClient.IsEncrypted(path.Join(toPath, name)) {
+		return fmt.Errorf("only one of them is encrypted")
+	}
+	remoteName := path.Base(acdClient.RemotePath(path.Join(toPath, name)))
+	// nothing is changed before the move is known to be valid.
+	if err := srcNode.CheckMove(toNode, remoteName); err != nil {
+		return err
+	}
+
+	if existing, err := findNode(path.Join(toPath, name)); err == nil && existing != srcNode {
+		switch {
+		case noClobber:
+			return nil
+		case existing.IsDir():
+			return fmt.Errorf("cannot overwrite directory %q", path.Join(toPath, name))
+		case srcNode.IsDir():
+			return fmt.Errorf("cannot overwrite non-directory %q with a directory", path.Join(toPath, name))
+		}
+		if err := acdClient.GetNodeTree().RemoveNode(existing); err != nil {
+			return err
+		}
+	}
+
+	// rename the node where it is unless the new name is already taken there,
+	// in which case it is moved first.
+	if _, err := findNode(path.Join(path.Dir(srcPath), name)); err == nil && !strings.EqualFold(remoteName, srcNode.Name) {
+		if err := srcNode.Move(fromNode, toNode); err != nil {
+			return err
+		}
+		return srcNode.Rename(remoteName)
+	}
+	if err := srcNode.Rename(remoteName); err != nil {
+		return err
+	}
+
+	return srcNode.Move(fromNode, toNode)
+}
+
+func mvBashComplete(c *cli.Context) {
+}
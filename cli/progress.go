@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/acd.v0"
+
+	"github.com/codegangsta/cli"
+)
+
+const (
+	// progressBarInterval is the minimum interval between two redraws of the
+	// progress bar on a terminal.
+	progressBarInterval = 200 * time.Millisecond
+
+	// progressLineInterval is the minimum interval between two progress lines
+	// when not on a terminal.
+	progressLineInterval = 10 * time.Second
+
+	progressBarWidth = 30
+)
+
+// newProgress returns a ProgressFunc printing the progress of a transfer to
+// stderr, as a bar redrawn in place on a terminal and as periodic plain lines
+// otherwise, and a function to call once the transfer is over. It returns a
+// nil ProgressFunc if --no-progress was given.
+func newProgress(c *cli.Context) (acd.ProgressFunc, func()) {
+	if c.Bool("no-progress") {
+		return nil, func() {}
+	}
+
+	var (
+		tty      = isTerminal(os.Stderr)
+		interval = progressLineInterval
+		last     time.Time
+		drawn    bool
+	)
+	if tty {
+		interval = progressBarInterval
+	}
+	progress := func(p acd.Progress) {
+		if p.Files < p.TotalFiles && time.Since(last) < interval {
+			return
+		}
+		last = time.Now()
+		if tty {
+			fmt.Fprintf(os.Stderr, "\r%s\033[K", progressBar(p))
+			drawn = true
+			return
+		}
+		fmt.Fprintln(os.Stderr, progressLine(p))
+	}
+	finish := func() {
+		if drawn {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	return progress, finish
+}
+
+// progressBar formats p as a progress bar followed by the details of
+// progressLine.
+func progressBar(p acd.Progress) string {
+	filled := int(progressRatio(p) * progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	return fmt.Sprintf("[%s] %s", bar, progressLine(p))
+}
+
+// progressLine formats p as a single line.
+func progressLine(p acd.Progress) string {
+	line := fmt.Sprintf("%3.0f%% %s/%s %d/%d files %s/s",
+		progressRatio(p)*100, humanSize(uint64(p.Bytes)), humanSize(uint64(p.TotalBytes)),
+		p.Files, p.TotalFiles, humanSize(uint64(p.Rate)))
+	if p.ETA > 0 {
+		line += fmt.Sprintf(" ETA %s", p.ETA/time.Second*time.Second)
+	}
+
+	return line + " " + p.Path
+}
+
+func progressRatio(p acd.Progress) float64 {
+	switch {
+	case p.TotalBytes > 0:
+		return float64(p.Bytes) / float64(p.TotalBytes)
+	case p.TotalFiles > 0:
+		return float64(p.Files) / float64(p.TotalFiles)
+	default:
+		return 0
+	}
+}
+
+// isTerminal returns whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
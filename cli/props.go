@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	ownerFlag = cli.StringFlag{
+		Name:  "owner, o",
+		Usage: "the owner of the properties, defaults to propertiesOwner of the configuration file",
+	}
+
+	propsCommand = cli.Command{
+		Name:        "props",
+		Usage:       "manage the application properties of nodes",
+		Description: "props reads, sets and deletes the application-specific properties of a node given by its acd:// path",
+		Subcommands: []cli.Command{
+			{
+				Name:   "get",
+				Usage:  "print the properties, all of them or the given keys",
+				Action: propsGetAction,
+				Flags: []cli.Flag{
+					ownerFlag,
+					cli.BoolFlag{
+						Name:  "fetch, f",
+						Usage: "fetch the properties from the server instead of the cache",
+					},
+					cli.BoolFlag{
+						Name:  "json",
+						Usage: "print the properties as a JSON object",
+					},
+				},
+			},
+			{
+				Name:   "set",
+				Usage:  "set properties given as key=value",
+				Action: propsSetAction,
+				Flags:  []cli.Flag{ownerFlag},
+			},
+			{
+				Name:   "delete",
+				Usage:  "delete the given keys",
+				Action: propsDeleteAction,
+				Flags:  []cli.Flag{ownerFlag},
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(propsCommand)
+}
+
+func propsGetAction(c *cli.Context) {
+	n, owner := propsNode(c)
+	props := n.Properties[owner]
+	if c.Bool("fetch") {
+		var err error
+		if props, err = n.FetchProperties(owner); err != nil {
+			log.Fatalf("props: %s", err)
+		}
+	}
+
+	if keys := c.Args()[1:]; len(keys) > 0 {
+		selected := make(map[string]string)
+		for _, key := range keys {
+			if value, found := props[key]; found {
+				selected[key] = value
+			}
+		}
+		props = selected
+	}
+
+	if c.Bool("json") {
+		json.NewEncoder(os.Stdout).Encode(props)
+		return
+	}
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("%s=%s\n", key, props[key])
+	}
+}
+
+func propsSetAction(c *cli.Context) {
+	n, owner := propsNode(c)
+	for _, arg := range c.Args()[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			log.Fatalf("props: %q is not of the form key=value", arg)
+		}
+		if err := n.SetProperty(owner, parts[0], parts[1]); err != nil {
+			log.Fatalf("props: cannot set %q: %s", parts[0], err)
+		}
+	}
+}
+
+func propsDeleteAction(c *cli.Context) {
+	n, owner := propsNode(c)
+	for _, key := range c.Args()[1:] {
+		if err := n.DeleteProperty(owner, key); err != nil {
+			log.Fatalf("props: cannot delete %q: %s", key, err)
+		}
+	}
+}
+
+// propsNode returns the node given as the first argument and the owner of the
+// properties.
+func propsNode(c *cli.Context) (*node.Node, string) {
+	if len(c.Args()) == 0 || !strings.HasPrefix(c.Args()[0], "acd://") {
+		log.Fatalf("props: a path prefixed by acd:// is required. Given: %v", c.Args())
+	}
+	owner := c.String("owner")
+	if owner == "" {
+		owner = acdClient.PropertiesOwner()
+	}
+	if owner == "" {
+		log.Fatalf("props: the owner is required, set propertiesOwner in the configuration file or use --owner")
+	}
+	n, err := acdClient.RemoteNode(strings.TrimPrefix(c.Args()[0], "acd://"))
+	if err != nil {
+		log.Fatalf("props: %s: %s", c.Args()[0], err)
+	}
+
+	return n, owner
+}
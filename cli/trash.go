@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+
+	"github.com/codegangsta/cli"
+)
+
+var (
+	trashCommand = cli.Command{
+		Name:        "trash",
+		Usage:       "manage the trash",
+		Description: "trash lists and restores the trashed nodes. A trashed node is given either by its ID or by its original path prefixed by acd://",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "list the trashed nodes with their original paths",
+				Action: trashListAction,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "json",
+						Usage: "print one JSON object per trashed node",
+					},
+				},
+			},
+			{
+				Name:   "restore",
+				Usage:  "restore trashed nodes",
+				Action: trashRestoreAction,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "to",
+						Usage: "restore into this acd:// folder instead of the original one",
+					},
+				},
+			},
+		},
+	}
+)
+
+func init() {
+	registerCommand(trashCommand)
+}
+
+func trashListAction(c *cli.Context) {
+	tns, err := acdClient.GetNodeTree().ListTrash()
+	if err != nil {
+		log.Fatalf("trash: %s", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, tn := range tns {
+		if c.Bool("json") {
+			enc.Encode(struct {
+				ID           string    `json:"id"`
+				Name         string    `json:"name"`
+				Kind         string    `json:"kind"`
+				Size         uint64    `json:"size"`
+				OriginalPath string    `json:"originalPath"`
+				TrashedDate  time.Time `json:"trashedDate"`
+			}{tn.ID, tn.Name, tn.Kind, tn.ContentProperties.Size, tn.OriginalPath, tn.TrashedDate})
+			continue
+		}
+
+		originalPath := tn.OriginalPath
+		if originalPath == "" {
+			originalPath = "?/" + tn.Name
+		}
+		fmt.Printf("%s\t%s\t%d\t%s\n", tn.ID, tn.TrashedDate, tn.Size(), originalPath)
+	}
+}
+
+func trashRestoreAction(c *cli.Context) {
+	var folder *node.Node
+	if to := c.String("to"); to != "" {
+		var err error
+		if folder, err = acdClient.GetNodeTree().MkdirAll(strings.TrimPrefix(to, "acd://")); err != nil {
+			log.Fatalf("trash: %s: %s", to, err)
+		}
+	}
+
+	for _, tn := range trashedNodes(c) {
+		if err := acdClient.GetNodeTree().Restore(tn.Node, folder); err != nil {
+			log.Fatalf("trash: cannot restore %s: %s", tn.ID, err)
+		}
+	}
+}
+
+// trashedNodes returns the trashed nodes given as arguments, by ID or by
+// original path.
+func trashedNodes(c *cli.Context) []*node.TrashedNode {
+	if len(c.Args()) == 0 {
+		log.Fatalf("trash: at least one ID or acd:// path is required")
+	}
+	tns, err := acdClient.GetNodeTree().ListTrash()
+	if err != nil {
+		log.Fatalf("trash: %s", err)
+	}
+
+	var found []*node.TrashedNode
+	for _, arg := range c.Args() {
+		var matched bool
+		originalPath := "/" + strings.Trim(strings.TrimPrefix(arg, "acd://"), "/")
+		for _, tn := range tns {
+			if tn.ID == arg || strings.HasPrefix(arg, "acd://") && strings.EqualFold(tn.OriginalPath, originalPath) {
+				found = append(found, tn)
+				matched = true
+			}
+		}
+		if !matched {
+			log.Fatalf("trash: %q not found in the trash", arg)
+		}
+	}
+
+	return found
+}
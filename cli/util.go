@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+)
+
+// humanSize returns size formatted with a binary unit, e.g. 1.5 GiB.
+func humanSize(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// parseAge parses a duration as time.ParseDuration does, and also accepts
+// a number of days or weeks such as 30d or 2w.
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseUint(strings.TrimSuffix(s, suffix), 10, 32)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+
+	return d, nil
+}
+
+// findNode finds the node at the remote path p, see (*acd.Client).RemoteNode,
+// without logging an error if it does not exist.
+func findNode(p string) (*node.Node, error) {
+	logLevel := log.GetLevel()
+	log.SetLevel(log.DisableLogLevel)
+	defer log.SetLevel(logLevel)
+
+	return acdClient.RemoteNode(p)
+}
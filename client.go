@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"golang.org/x/oauth2"
+	"gopkg.in/acd.v0/crypt"
 	"gopkg.in/acd.v0/internal/constants"
 	"gopkg.in/acd.v0/internal/log"
 	"gopkg.in/acd.v0/node"
@@ -35,6 +36,16 @@ type (
 		// will cancel the request and return. A timeout of 0 (the default) means
 		// no timeout. See http://godoc.org/net/http#Client for more information.
 		Timeout time.Duration `json:"timeout"`
+
+		// PropertiesOwner is the owner of the application-specific properties
+		// read and written by the client, see node.Properties. Amazon only lets
+		// an application write the properties it owns so this must be the ID of
+		// the application the token was issued for.
+		PropertiesOwner string `json:"propertiesOwner"`
+
+		// Encryption configures the client-side encryption of a folder, see
+		// EncryptionConfig. Nothing is encrypted when Encryption.Root is empty.
+		Encryption EncryptionConfig `json:"encryption"`
 	}
 
 	// Client provides a client for Amazon Cloud Drive.
@@ -45,6 +56,7 @@ type (
 		NodeTree *node.Tree
 
 		config      *Config
+		cipher      *crypt.Cipher
 		httpClient  *http.Client
 		cacheFile   string
 		metadataURL string
@@ -81,6 +93,9 @@ func New(configFile string) (*Client, error) {
 			},
 		},
 	}
+	if c.cipher, err = newCipher(&config.Encryption); err != nil {
+		return nil, err
+	}
 	if err := setEndpoints(c); err != nil {
 		return nil, err
 	}
@@ -93,6 +108,12 @@ func (c *Client) Close() error {
 	return c.NodeTree.Close()
 }
 
+// PropertiesOwner returns the owner of the properties as configured by
+// Config.PropertiesOwner.
+func (c *Client) PropertiesOwner() string {
+	return c.config.PropertiesOwner
+}
+
 // Do invokes net/http.Client.Do(). Refer to net/http.Client.Do() for documentation.
 func (c *Client) Do(r *http.Request) (*http.Response, error) {
 	return c.httpClient.Do(r)
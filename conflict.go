@@ -0,0 +1,157 @@
+package acd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// ConflictPolicy is what an upload does when its target already exists, see
+// UploadOptions.Conflict and UploadOptions.FolderConflict.
+type ConflictPolicy int
+
+const (
+	// ConflictDefault follows UploadOptions.Overwrite: an upload overwrites
+	// the existing file, a folder upload only the files whose content
+	// differs, otherwise the upload fails like with ConflictFail.
+	ConflictDefault ConflictPolicy = iota
+
+	// ConflictSkip keeps the existing file and skips the upload.
+	ConflictSkip
+
+	// ConflictOverwrite overwrites the existing file, or trashes the existing
+	// folder before uploading the file.
+	ConflictOverwrite
+
+	// ConflictOverwriteIfNewer overwrites the existing file if the local file
+	// was modified after it, or if the local modification time is unknown.
+	ConflictOverwriteIfNewer
+
+	// ConflictOverwriteIfDifferent overwrites the existing file if its
+	// content differs, an existing folder always differs. The content of an
+	// upload is only compared if it can seek, it is overwritten otherwise.
+	ConflictOverwriteIfDifferent
+
+	// ConflictRename uploads the file next to the existing one, named after
+	// it followed by the first free number, e.g. name (1).ext.
+	ConflictRename
+
+	// ConflictFail returns an error. A folder upload skips the files whose
+	// content is the same.
+	ConflictFail
+)
+
+// conflictPolicy returns the policy applying to an existing file, or an
+// existing folder if isDir is set. folder is set for a folder upload.
+func (opts *UploadOptions) conflictPolicy(isDir, folder bool) ConflictPolicy {
+	switch {
+	case isDir && opts.FolderConflict != ConflictDefault:
+		return opts.FolderConflict
+	case isDir:
+		return ConflictFail
+	case opts.Conflict != ConflictDefault:
+		return opts.Conflict
+	case opts.Overwrite && folder:
+		return ConflictOverwriteIfDifferent
+	case opts.Overwrite:
+		return ConflictOverwrite
+	default:
+		return ConflictFail
+	}
+}
+
+// resolveConflict returns ConflictSkip, ConflictOverwrite or ConflictRename
+// for the file ft whose target ft.node exists, according to opts. r is the
+// content of the file, compared with the existing file if it is not nil; it
+// is rewound. folder is set for a folder upload.
+func (c *Client) resolveConflict(ft fileTransfer, r io.ReadSeeker, opts *UploadOptions, folder bool) (ConflictPolicy, error) {
+	isDir := ft.node.IsDir()
+	switch policy := opts.conflictPolicy(isDir, folder); policy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return policy, nil
+	case ConflictOverwriteIfNewer:
+		if ft.modTime.IsZero() || ft.modTime.After(ft.node.ModifiedDate) {
+			return ConflictOverwrite, nil
+		}
+		return ConflictSkip, nil
+	case ConflictOverwriteIfDifferent:
+		if isDir || r == nil {
+			return ConflictOverwrite, nil
+		}
+		same, err := c.sameContent(ft, r)
+		if err != nil {
+			return 0, err
+		}
+		if same {
+			return ConflictSkip, nil
+		}
+		return ConflictOverwrite, nil
+	default:
+		if isDir {
+			log.Errorf("%s: remoteFilename %q", constants.ErrFileExistsAndIsFolder, ft.remotePath)
+			return 0, constants.ErrFileExistsAndIsFolder
+		}
+		if !folder || r == nil {
+			log.Errorf("%s: %s", constants.ErrFileExists, ft.remotePath)
+			return 0, constants.ErrFileExists
+		}
+		same, err := c.sameContent(ft, r)
+		if err != nil {
+			return 0, err
+		}
+		if same {
+			return ConflictSkip, nil
+		}
+		log.Errorf("%s: remoteFilename %q", constants.ErrFileExistsWithDifferentContents, ft.remotePath)
+		return 0, constants.ErrFileExistsWithDifferentContents
+	}
+}
+
+// sameContent returns whether r has the content of the existing file ft.node,
+// r is rewound.
+func (c *Client) sameContent(ft fileTransfer, r io.ReadSeeker) (bool, error) {
+	want := ft.node.ContentProperties.MD5
+	if ft.chunked {
+		m, err := c.readManifest(ft.remotePath)
+		if err != nil {
+			return false, err
+		}
+		want = m.MD5
+	}
+	sum, err := c.contentMD5(ft, r)
+	if err != nil {
+		return false, err
+	}
+	if _, err := r.Seek(0, os.SEEK_SET); err != nil {
+		log.Errorf("%s: %s", constants.ErrReadingFileContents, err)
+		return false, constants.ErrReadingFileContents
+	}
+
+	return sum == want, nil
+}
+
+// renamedPath returns the first free path named after the plain path p
+// followed by a number, e.g. /folder/name (1).ext.
+func (c *Client) renamedPath(p string) string {
+	var (
+		dir, base = path.Split(p)
+		ext       = path.Ext(base)
+		stem      = strings.TrimSuffix(base, ext)
+	)
+	for i := 1; ; i++ {
+		candidate := path.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+		if _, err := c.findSilently(candidate); err == nil {
+			continue
+		}
+		if _, err := c.findSilently(candidate + ChunkedSuffix); err == nil {
+			continue
+		}
+
+		return candidate
+	}
+}
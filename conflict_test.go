@@ -0,0 +1,70 @@
+package acd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestResolveConflict(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	readme, err := c.NodeTree.FindNode("/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pictures, err := c.NodeTree.FindNode("/pictures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts    UploadOptions
+		node    *node.Node
+		modTime time.Time
+		folder  bool
+		want    ConflictPolicy
+		err     error
+	}{
+		{UploadOptions{}, readme, time.Time{}, false, 0, constants.ErrFileExists},
+		{UploadOptions{Overwrite: true}, readme, time.Time{}, false, ConflictOverwrite, nil},
+		{UploadOptions{Overwrite: true}, readme, time.Time{}, true, ConflictOverwrite, nil},
+		{UploadOptions{}, readme, time.Time{}, true, 0, constants.ErrFileExistsWithDifferentContents},
+		{UploadOptions{Conflict: ConflictSkip}, readme, time.Time{}, false, ConflictSkip, nil},
+		{UploadOptions{Conflict: ConflictRename}, readme, time.Time{}, false, ConflictRename, nil},
+		{UploadOptions{Conflict: ConflictOverwriteIfNewer}, readme, readme.ModifiedDate.Add(time.Hour), false, ConflictOverwrite, nil},
+		{UploadOptions{Conflict: ConflictOverwriteIfNewer}, readme, readme.ModifiedDate.Add(-time.Hour), false, ConflictSkip, nil},
+		{UploadOptions{Conflict: ConflictOverwrite}, pictures, time.Time{}, false, 0, constants.ErrFileExistsAndIsFolder},
+		{UploadOptions{FolderConflict: ConflictOverwrite}, pictures, time.Time{}, false, ConflictOverwrite, nil},
+		{UploadOptions{FolderConflict: ConflictSkip}, pictures, time.Time{}, true, ConflictSkip, nil},
+	}
+
+	for i, test := range tests {
+		ft := fileTransfer{node: test.node, remotePath: "/" + test.node.Name, modTime: test.modTime}
+		got, err := c.resolveConflict(ft, strings.NewReader("new content"), &test.opts, test.folder)
+		if err != test.err || (err == nil && got != test.want) {
+			t.Errorf("#%d: c.resolveConflict(): want %v %v got %v %v", i, test.want, test.err, got, err)
+		}
+	}
+}
+
+func TestRenamedPath(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	tests := map[string]string{
+		"/README.md":         "/README (1).md",
+		"/pictures":          "/pictures (1)",
+		"/pictures/logo.png": "/pictures/logo (1).png",
+	}
+
+	for p, want := range tests {
+		if got := c.renamedPath(p); got != want {
+			t.Errorf("c.renamedPath(%q): want %q got %q", p, want, got)
+		}
+	}
+}
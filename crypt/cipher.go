@@ -0,0 +1,120 @@
+// Package crypt encrypts the content and the names of the files stored on the
+// Amazon Cloud Drive so that Amazon cannot read them.
+//
+// The content is encrypted with AES-256-GCM in chunks of ChunkSize bytes,
+// each file using its own key derived from a random salt stored in its
+// header, so it can be decrypted from any chunk. The names are encrypted
+// deterministically with AES-256-CTR using a synthetic IV computed with
+// HMAC-SHA256 so that a path always encrypts to the same path and can be
+// found in the NodeTree.
+package crypt // import "gopkg.in/acd.v0/crypt"
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+const (
+	// KeySize is the size of the master key.
+	KeySize = 32
+
+	// passphraseSalt salts the key derived from a passphrase. It is constant
+	// so that the same passphrase always gives the same key, on any machine.
+	passphraseSalt = "gopkg.in/acd.v0/crypt passphrase"
+
+	// passphraseIterations is the number of PBKDF2 iterations deriving the
+	// key from a passphrase.
+	passphraseIterations = 200000
+)
+
+// Cipher encrypts and decrypts content and names with the keys derived from a
+// master key. It is safe for concurrent use.
+type Cipher struct {
+	contentKey []byte
+	nameBlock  cipher.Block
+	nameMACKey []byte
+}
+
+// New returns a Cipher using the master key, which must be KeySize bytes.
+func New(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		log.Errorf("%s: want %d bytes got %d", constants.ErrInvalidEncryptionKey, KeySize, len(key))
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+
+	c := new(Cipher)
+	var (
+		nameKey []byte
+		err     error
+	)
+	for _, subkey := range []struct {
+		info string
+		key  *[]byte
+	}{
+		{"content", &c.contentKey},
+		{"name", &nameKey},
+		{"name mac", &c.nameMACKey},
+	} {
+		if *subkey.key, err = deriveKey(key, nil, subkey.info); err != nil {
+			log.Errorf("%s: %s", constants.ErrInvalidEncryptionKey, err)
+			return nil, constants.ErrInvalidEncryptionKey
+		}
+	}
+	if c.nameBlock, err = aes.NewCipher(nameKey); err != nil {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptionKey, err)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+
+	return c, nil
+}
+
+// KeyFromPassphrase derives a master key from a passphrase.
+func KeyFromPassphrase(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		log.Errorf("%s: empty passphrase", constants.ErrInvalidEncryptionKey)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+
+	return pbkdf2.Key([]byte(passphrase), []byte(passphraseSalt), passphraseIterations, KeySize, sha256.New), nil
+}
+
+// deriveKey derives a KeySize key for info from secret and salt with
+// HKDF-SHA256.
+func deriveKey(secret, salt []byte, info string) ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ReadKeyFile reads a master key from a file holding either KeySize raw
+// bytes or their hex encoding.
+func ReadKeyFile(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrOpenFile, err)
+		return nil, constants.ErrOpenFile
+	}
+	if len(content) == KeySize {
+		return content, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != KeySize {
+		log.Errorf("%s: %s must hold %d bytes, raw or hex-encoded", constants.ErrInvalidEncryptionKey, path, KeySize)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+
+	return key, nil
+}
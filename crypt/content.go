@@ -0,0 +1,329 @@
+package crypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+const (
+	// ChunkSize is the size of the plain content of a chunk, the last chunk
+	// of a file may be smaller.
+	ChunkSize = 64 * 1024
+
+	// Overhead is the number of bytes added to every chunk by the
+	// authentication.
+	Overhead = 16
+
+	// HeaderSize is the size of the header starting the encrypted content.
+	HeaderSize = len(magic) + saltSize
+
+	magic    = "ACDCRYPT\x00\x01"
+	saltSize = 22
+
+	encryptedChunkSize = ChunkSize + Overhead
+)
+
+type (
+	// encryptingReader encrypts the content of src chunk by chunk.
+	encryptingReader struct {
+		src     io.Reader
+		r       *bufio.Reader
+		aead    cipher.AEAD
+		header  []byte
+		chunk   []byte
+		out     []byte
+		counter uint64
+		done    bool
+	}
+
+	// decryptingReader decrypts the content of r chunk by chunk.
+	decryptingReader struct {
+		r       *bufio.Reader
+		aead    cipher.AEAD
+		chunk   []byte
+		out     []byte
+		counter uint64
+		done    bool
+	}
+
+	// DecryptingReaderAt decrypts an encrypted content from any offset.
+	DecryptingReaderAt struct {
+		ra            io.ReaderAt
+		aead          cipher.AEAD
+		encryptedSize int64
+		size          int64
+	}
+)
+
+// EncryptedSize returns the size of the encrypted content of size bytes.
+func EncryptedSize(size int64) int64 {
+	chunks := (size + ChunkSize - 1) / ChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+
+	return int64(HeaderSize) + size + chunks*Overhead
+}
+
+// DecryptedSize returns the size of the plain content of an encrypted content
+// of size bytes.
+func DecryptedSize(size int64) (int64, error) {
+	size -= int64(HeaderSize)
+	chunks, rest := size/encryptedChunkSize, size%encryptedChunkSize
+	switch {
+	case size < Overhead:
+		log.Errorf("%s: too short", constants.ErrInvalidEncryptedContent)
+		return 0, constants.ErrInvalidEncryptedContent
+	case rest == 0:
+		return chunks * ChunkSize, nil
+	case rest < Overhead:
+		log.Errorf("%s: truncated chunk", constants.ErrInvalidEncryptedContent)
+		return 0, constants.ErrInvalidEncryptedContent
+	default:
+		return chunks*ChunkSize + rest - Overhead, nil
+	}
+}
+
+// NewEncryptingReader returns a reader of the encrypted content of r, with a
+// new random salt. The reader can be rewound with Seek(0, os.SEEK_SET) if r
+// is an io.Seeker.
+func (c *Cipher) NewEncryptingReader(r io.Reader) (io.Reader, error) {
+	header := make([]byte, HeaderSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptionKey, err)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+
+	return c.NewEncryptingReaderWithHeader(r, header)
+}
+
+// NewEncryptingReaderWithHeader returns a reader of the encrypted content of
+// r reusing the salt of the header of another encrypted content. The same
+// content encrypted with the same header gives the same encrypted content, it
+// is meant to check whether a local file matches an encrypted remote file.
+func (c *Cipher) NewEncryptingReaderWithHeader(r io.Reader, header []byte) (io.Reader, error) {
+	aead, err := c.fileAEAD(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptingReader{
+		src:    r,
+		r:      bufio.NewReaderSize(r, ChunkSize+1),
+		aead:   aead,
+		header: header,
+		chunk:  make([]byte, ChunkSize),
+		out:    append([]byte{}, header...),
+	}, nil
+}
+
+// NewDecryptingReader returns a reader of the plain content of the encrypted
+// content r. A chunk failing authentication, a truncated or an extended
+// content make the reader return constants.ErrInvalidEncryptedContent.
+func (c *Cipher) NewDecryptingReader(r io.Reader) (io.Reader, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		log.Errorf("%s: reading the header: %s", constants.ErrInvalidEncryptedContent, err)
+		return nil, constants.ErrInvalidEncryptedContent
+	}
+	aead, err := c.fileAEAD(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{
+		r:     bufio.NewReaderSize(r, encryptedChunkSize+1),
+		aead:  aead,
+		chunk: make([]byte, encryptedChunkSize),
+	}, nil
+}
+
+// NewDecryptingReaderAt returns a DecryptingReaderAt of the encrypted content
+// of size bytes read from ra.
+func (c *Cipher) NewDecryptingReaderAt(ra io.ReaderAt, size int64) (*DecryptingReaderAt, error) {
+	plainSize, err := DecryptedSize(size)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, HeaderSize)
+	if _, err := ra.ReadAt(header, 0); err != nil {
+		log.Errorf("%s: reading the header: %s", constants.ErrInvalidEncryptedContent, err)
+		return nil, constants.ErrInvalidEncryptedContent
+	}
+	aead, err := c.fileAEAD(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecryptingReaderAt{
+		ra:            ra,
+		aead:          aead,
+		encryptedSize: size,
+		size:          plainSize,
+	}, nil
+}
+
+func (er *encryptingReader) Read(p []byte) (int, error) {
+	for len(er.out) == 0 {
+		if er.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(er.r, er.chunk)
+		switch err {
+		case nil:
+			_, err = er.r.Peek(1)
+			er.done = err == io.EOF
+			if err != nil && err != io.EOF {
+				return 0, err
+			}
+		case io.EOF, io.ErrUnexpectedEOF:
+			er.done = true
+		default:
+			return 0, err
+		}
+		er.out = er.aead.Seal(er.out[:0], chunkNonce(er.counter, er.done), er.chunk[:n], nil)
+		er.counter++
+	}
+	n := copy(p, er.out)
+	er.out = er.out[n:]
+
+	return n, nil
+}
+
+// Seek rewinds the encrypted content so that it can be uploaded again. Only
+// seeking to the start of a content read from an io.Seeker is supported.
+func (er *encryptingReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := er.src.(io.Seeker)
+	if !ok || offset != 0 || whence != os.SEEK_SET {
+		return 0, constants.ErrInvalidSeek
+	}
+	if _, err := seeker.Seek(0, os.SEEK_SET); err != nil {
+		return 0, err
+	}
+	er.r.Reset(er.src)
+	er.out = append([]byte{}, er.header...)
+	er.counter = 0
+	er.done = false
+
+	return 0, nil
+}
+
+func (dr *decryptingReader) Read(p []byte) (int, error) {
+	for len(dr.out) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(dr.r, dr.chunk)
+		switch err {
+		case nil:
+			_, err = dr.r.Peek(1)
+			dr.done = err == io.EOF
+			if err != nil && err != io.EOF {
+				return 0, err
+			}
+		case io.EOF, io.ErrUnexpectedEOF:
+			dr.done = true
+		default:
+			return 0, err
+		}
+		out, err := dr.aead.Open(dr.out[:0], chunkNonce(dr.counter, dr.done), dr.chunk[:n], nil)
+		if err != nil {
+			log.Errorf("%s: chunk %d: %s", constants.ErrInvalidEncryptedContent, dr.counter, err)
+			return 0, constants.ErrInvalidEncryptedContent
+		}
+		dr.out = out
+		dr.counter++
+	}
+	n := copy(p, dr.out)
+	dr.out = dr.out[n:]
+
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes of the plain content from offset off. Only the
+// chunks holding them are read and decrypted.
+func (dra *DecryptingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, constants.ErrInvalidSeek
+	}
+	var (
+		read  int
+		chunk = make([]byte, encryptedChunkSize)
+		plain = make([]byte, 0, ChunkSize)
+	)
+	for read < len(p) {
+		if off >= dra.size {
+			return read, io.EOF
+		}
+		index := off / ChunkSize
+		start := int64(HeaderSize) + index*encryptedChunkSize
+		end := start + encryptedChunkSize
+		if end > dra.encryptedSize {
+			end = dra.encryptedSize
+		}
+		if _, err := dra.ra.ReadAt(chunk[:end-start], start); err != nil && err != io.EOF {
+			return read, err
+		}
+		decrypted, err := dra.aead.Open(plain[:0], chunkNonce(uint64(index), end == dra.encryptedSize), chunk[:end-start], nil)
+		if err != nil {
+			log.Errorf("%s: chunk %d: %s", constants.ErrInvalidEncryptedContent, index, err)
+			return read, constants.ErrInvalidEncryptedContent
+		}
+		n := copy(p[read:], decrypted[off-index*ChunkSize:])
+		read += n
+		off += int64(n)
+	}
+
+	return read, nil
+}
+
+// Size returns the size of the plain content.
+func (dra *DecryptingReaderAt) Size() int64 {
+	return dra.size
+}
+
+// fileAEAD returns the AEAD of the content starting with header.
+func (c *Cipher) fileAEAD(header []byte) (cipher.AEAD, error) {
+	if len(header) != HeaderSize || string(header[:len(magic)]) != magic {
+		log.Errorf("%s: invalid header", constants.ErrInvalidEncryptedContent)
+		return nil, constants.ErrInvalidEncryptedContent
+	}
+	key, err := deriveKey(c.contentKey, header[len(magic):], "file")
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptionKey, err)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptionKey, err)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptionKey, err)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+
+	return aead, nil
+}
+
+// chunkNonce returns the nonce of the chunk number counter. The nonce of the
+// last chunk differs so that a truncated content does not authenticate.
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, counter)
+	if last {
+		nonce[11] = 1
+	}
+
+	return nonce
+}
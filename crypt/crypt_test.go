@@ -0,0 +1,141 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func newTestCipher(t *testing.T) *Cipher {
+	c, err := New(bytes.Repeat([]byte{42}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestContent(t *testing.T) {
+	c := newTestCipher(t)
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 42} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		r, err := c.NewEncryptingReader(bytes.NewReader(plain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: encrypting error: %s", size, err)
+		}
+		if want, got := EncryptedSize(int64(size)), int64(len(encrypted)); want != got {
+			t.Errorf("EncryptedSize(%d): want %d got %d", size, want, got)
+		}
+		if got, err := DecryptedSize(int64(len(encrypted))); err != nil || got != int64(size) {
+			t.Errorf("DecryptedSize(%d): want %d got %d %v", len(encrypted), size, got, err)
+		}
+
+		dr, err := c.NewDecryptingReader(bytes.NewReader(encrypted))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := ioutil.ReadAll(dr)
+		if err != nil || !bytes.Equal(plain, decrypted) {
+			t.Errorf("size %d: the decrypted content does not match: %v", size, err)
+		}
+
+		dra, err := c.NewDecryptingReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, off := range []int{0, 1, ChunkSize - 1, ChunkSize, size / 2} {
+			if off >= size {
+				continue
+			}
+			p := make([]byte, 2*ChunkSize)
+			n, err := dra.ReadAt(p, int64(off))
+			if err != nil && err != io.EOF {
+				t.Errorf("size %d: ReadAt(%d) error: %s", size, off, err)
+			}
+			if !bytes.Equal(plain[off:off+n], p[:n]) || (n < len(p) && off+n != size) {
+				t.Errorf("size %d: ReadAt(%d) does not match", size, off)
+			}
+		}
+	}
+}
+
+func TestContentTampered(t *testing.T) {
+	c := newTestCipher(t)
+	plain := make([]byte, 2*ChunkSize+10)
+	r, err := c.NewEncryptingReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, _ := ioutil.ReadAll(r)
+
+	tests := map[string][]byte{
+		"flipped":   append(append([]byte{}, encrypted[:HeaderSize+10]...), append([]byte{encrypted[HeaderSize+10] ^ 1}, encrypted[HeaderSize+11:]...)...),
+		"truncated": encrypted[:HeaderSize+encryptedChunkSize],
+		"extended":  append(append([]byte{}, encrypted...), encrypted[HeaderSize:HeaderSize+encryptedChunkSize]...),
+	}
+	for name, content := range tests {
+		dr, err := c.NewDecryptingReader(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ioutil.ReadAll(dr); err != constants.ErrInvalidEncryptedContent {
+			t.Errorf("%s: want %s got %v", name, constants.ErrInvalidEncryptedContent, err)
+		}
+	}
+}
+
+func TestContentSeek(t *testing.T) {
+	c := newTestCipher(t)
+	plain := []byte("hello world")
+	r, err := c.NewEncryptingReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := ioutil.ReadAll(r)
+	if _, err := r.(io.Seeker).Seek(0, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := ioutil.ReadAll(r); !bytes.Equal(first, second) {
+		t.Errorf("the content encrypted again does not match")
+	}
+}
+
+func TestName(t *testing.T) {
+	c := newTestCipher(t)
+	// the names of 1 to 5 bytes cover every length of padding.
+	for _, name := range []string{"README.md", "a", "ab", "abc", "abcd", "abcde", "ünïcödé name.tar.gz"} {
+		encrypted := c.EncryptName(name)
+		if strings.Contains(encrypted, "=") {
+			t.Errorf("EncryptName(%q): %q is padded", name, encrypted)
+		}
+		if again := c.EncryptName(name); encrypted != again {
+			t.Errorf("EncryptName(%q) is not deterministic: %q and %q", name, encrypted, again)
+		}
+		if got, err := c.DecryptName(encrypted); err != nil || got != name {
+			t.Errorf("DecryptName(%q): want %q got %q %v", encrypted, name, got, err)
+		}
+	}
+
+	if _, err := c.DecryptName("not-encrypted.txt"); err != constants.ErrInvalidEncryptedName {
+		t.Errorf("DecryptName of a plain name: want %s got %v", constants.ErrInvalidEncryptedName, err)
+	}
+	other, err := New(bytes.Repeat([]byte{7}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.DecryptName(c.EncryptName("secret")); err != constants.ErrInvalidEncryptedName {
+		t.Errorf("DecryptName with another key: want %s got %v", constants.ErrInvalidEncryptedName, err)
+	}
+}
@@ -0,0 +1,101 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// nameEncoding encodes the encrypted names in lower-case as the names of the
+// Amazon Cloud Drive are case-insensitive. The padding is stripped from the
+// names, see encodeName and decodeName.
+var nameEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567")
+
+// EncryptName encrypts the name of a file or a folder. The same name always
+// encrypts to the same name.
+func (c *Cipher) EncryptName(name string) string {
+	iv := c.nameIV(name)
+	encrypted := make([]byte, len(iv)+len(name))
+	copy(encrypted, iv)
+	cipher.NewCTR(c.nameBlock, iv).XORKeyStream(encrypted[len(iv):], []byte(name))
+
+	return encodeName(encrypted)
+}
+
+// DecryptName decrypts a name encrypted by EncryptName.
+func (c *Cipher) DecryptName(name string) (string, error) {
+	encrypted, err := decodeName(strings.ToLower(name))
+	if err != nil || len(encrypted) < aes.BlockSize {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptedName, name)
+		return "", constants.ErrInvalidEncryptedName
+	}
+
+	iv, decrypted := encrypted[:aes.BlockSize], make([]byte, len(encrypted)-aes.BlockSize)
+	cipher.NewCTR(c.nameBlock, iv).XORKeyStream(decrypted, encrypted[aes.BlockSize:])
+	if !hmac.Equal(iv, c.nameIV(string(decrypted))) {
+		log.Errorf("%s: %s", constants.ErrInvalidEncryptedName, name)
+		return "", constants.ErrInvalidEncryptedName
+	}
+
+	return string(decrypted), nil
+}
+
+// EncryptPath encrypts every element of the slash-separated path p.
+func (c *Cipher) EncryptPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = c.EncryptName(part)
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// DecryptPath decrypts every element of the slash-separated path p.
+func (c *Cipher) DecryptPath(p string) (string, error) {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		decrypted, err := c.DecryptName(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = decrypted
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+// nameIV returns the synthetic IV of name, which also authenticates it.
+func (c *Cipher) nameIV(name string) []byte {
+	mac := hmac.New(sha256.New, c.nameMACKey)
+	mac.Write([]byte(name))
+
+	return mac.Sum(nil)[:aes.BlockSize]
+}
+
+// encodeName encodes b with nameEncoding without the trailing padding.
+func encodeName(b []byte) string {
+	return strings.TrimRight(nameEncoding.EncodeToString(b), "=")
+}
+
+// decodeName decodes a name encoded by encodeName, padding it back first.
+func decodeName(s string) ([]byte, error) {
+	if strings.Contains(s, "=") {
+		return nil, base32.CorruptInputError(strings.Index(s, "="))
+	}
+	if n := len(s) % 8; n != 0 {
+		s += strings.Repeat("=", 8-n)
+	}
+
+	return nameEncoding.DecodeString(s)
+}
@@ -1,13 +1,17 @@
 package acd
 
 import (
-	"fmt"
 	"io"
 	"os"
 	"path"
+	"sort"
+	"strings"
+	"time"
 
+	"gopkg.in/acd.v0/filter"
 	"gopkg.in/acd.v0/internal/constants"
 	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
 )
 
 // Download returns an io.ReadCloser for path. The caller is responsible for
@@ -15,58 +19,327 @@ import (
 func (c *Client) Download(path string) (io.ReadCloser, error) {
 	log.Debugf("downloading %q", path)
 
-	node, err := c.NodeTree.FindNode(path)
+	node, chunked, err := c.findNode(path)
 	if err != nil {
 		return nil, err
 	}
+	if chunked {
+		return c.downloadChunked(path)
+	}
+	body, err := node.Download()
+	if err != nil || !c.isEncrypted(path) {
+		return body, err
+	}
+	r, err := c.cipher.NewDecryptingReader(body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return readCloser{Reader: r, Closer: body}, nil
+}
+
+// Open opens the file at path for random-access reads of its content as
+// stored on the drive. It fails with ErrNotPlainFile if the file is encrypted
+// or stored in chunks, see OpenFile. The caller is responsible for closing
+// the file.
+func (c *Client) Open(path string) (*node.File, error) {
+	log.Debugf("opening %q", path)
+
+	node, chunked, err := c.findNode(path)
+	if err != nil {
+		return nil, err
+	}
+	if chunked || c.isEncrypted(path) {
+		log.Errorf("%s: %s, use OpenFile", constants.ErrNotPlainFile, path)
+		return nil, constants.ErrNotPlainFile
+	}
+
+	return node.Open()
+}
+
+// OpenFile opens the file at path for random-access reads like Open, the
+// content of an encrypted file being decrypted and a file stored in chunks
+// read as a single file. The caller is responsible for closing the file.
+func (c *Client) OpenFile(path string) (File, error) {
+	log.Debugf("opening %q", path)
+
+	node, chunked, err := c.findNode(path)
+	if err != nil {
+		return nil, err
+	}
+	if chunked {
+		return c.openChunked(path)
+	}
+	f, err := node.Open()
+	if err != nil || !c.isEncrypted(path) {
+		return f, err
+	}
+	ra, err := c.cipher.NewDecryptingReaderAt(f, f.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
 
-	return node.Download()
+	return sectionFile{SectionReader: io.NewSectionReader(ra, 0, ra.Size()), Closer: f}, nil
+}
+
+// DownloadOptions are the options of (*Client).DownloadFolderWithOptions.
+type DownloadOptions struct {
+	// Recursive downloads the sub-folders of a folder as well.
+	Recursive bool
+
+	// Jobs is the number of files downloaded at the same time, one when
+	// zero.
+	Jobs int
+
+	// Progress, unless nil, is called with the progress of the download.
+	Progress ProgressFunc `json:"-"`
+
+	// SkipVerify does not verify the MD5 of the downloaded files, their size
+	// is checked all the same.
+	SkipVerify bool
+
+	// Filter, unless nil, selects the files downloaded by
+	// (*Client).DownloadFolderWithOptions.
+	Filter *filter.Filter `json:"-"`
+
+	// Preserve restores the modification time, the mode, the owner and the
+	// group recorded by UploadOptions.Preserve, as well as the extended
+	// attributes if Xattrs is set. The modification time falls back to the
+	// dates of the node when it was not recorded.
+	Preserve, Xattrs bool
+
+	// Symlinks is what a folder download does with the markers of the
+	// symbolic links, see SymlinkPolicy. They are only restored as links
+	// with SymlinkStore.
+	Symlinks SymlinkPolicy
+}
+
+// DownloadToFile downloads the file remotePath to the file localPath
+// according to opts, see (*node.Node).DownloadFile.
+func (c *Client) DownloadToFile(localPath, remotePath string, opts *DownloadOptions) error {
+	log.Debugf("downloading %q to %q", remotePath, localPath)
+
+	node, chunked, err := c.findNode(remotePath)
+	if err != nil {
+		return err
+	}
+	ft := fileTransfer{node: node, localPath: localPath, remotePath: remotePath, size: node.Size(), chunked: chunked}
+	if chunked {
+		ft.size = c.chunkedNode(remotePath, node).Size()
+	}
+
+	return c.downloadFile(ft, opts, newProgressTracker(opts.Progress, []fileTransfer{ft}))
 }
 
 // DownloadFolder downloads an entire folder to a path, if recursive is true,
 // it will also download all subfolders.
 func (c *Client) DownloadFolder(localPath, remotePath string, recursive bool) error {
+	return c.DownloadFolderWithOptions(localPath, remotePath, &DownloadOptions{Recursive: recursive})
+}
+
+// DownloadFolderWithOptions downloads an entire folder to a path according to
+// opts. The folders are created first, parents before their children, then
+// the files are downloaded by opts.Jobs workers. A file failing to download
+// does not stop the others, the failures are returned as TransferErrors.
+func (c *Client) DownloadFolderWithOptions(localPath, remotePath string, opts *DownloadOptions) error {
 	log.Debugf("downloading %q to %q", localPath, remotePath)
 
-	if err := os.Mkdir(localPath, os.FileMode(0755)); err != nil && !os.IsExist(err) {
-		log.Errorf("%s: %s", constants.ErrCreateFolder, err)
-		return constants.ErrCreateFolder
-	}
-	rootNode, err := c.GetNodeTree().FindNode(remotePath)
+	files, errs, err := c.prepareDownload(localPath, remotePath, opts)
 	if err != nil {
+		return err
+	}
+
+	return c.downloadFiles(files, errs, opts)
+}
+
+// downloadFiles downloads files on opts.Jobs workers. The failures are
+// returned as TransferErrors along with errs.
+func (c *Client) downloadFiles(files []fileTransfer, errs TransferErrors, opts *DownloadOptions) error {
+	tracker := newProgressTracker(opts.Progress, files)
+	downloadErrs := parallel(opts.Jobs, len(files), func(i int) error {
+		return c.downloadFile(files[i], opts, tracker)
+	})
+	for i, err := range downloadErrs {
+		if err != nil {
+			errs = append(errs, &TransferError{Path: files[i].remotePath, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		sort.Sort(byTransferPath(errs))
+		return errs
+	}
+
+	return nil
+}
+
+type (
+	fileTransfer struct {
+		node       *node.Node
+		localPath  string
+		remotePath string
+		size       int64
+		modTime    time.Time
+
+		// chunked is set if node is the manifest of a file stored in chunks.
+		chunked bool
+
+		// mode is the mode of the local file, os.ModeSymlink being set for a
+		// symbolic link stored as a marker, see SymlinkStore.
+		mode os.FileMode
+	}
+
+	byTransferPath TransferErrors
+)
+
+// downloadFile downloads the file ft according to opts, restores its
+// attributes and reports its progress to tracker.
+func (c *Client) downloadFile(ft fileTransfer, opts *DownloadOptions, tracker *progressTracker) error {
+	log.Debugf("saving %s as %s", ft.remotePath, ft.localPath)
+	if ft.mode&os.ModeSymlink != 0 {
+		if err := c.restoreSymlink(ft); err != nil {
+			return err
+		}
+		tracker.done(ft)
 		return nil
 	}
-	for _, node := range rootNode.Nodes {
-		flp := path.Join(localPath, node.Name)
-		frp := fmt.Sprintf("%s/%s", remotePath, node.Name)
-		if node.IsDir() {
-			if recursive {
-				if err := c.DownloadFolder(flp, frp, recursive); err != nil {
-					return err
-				}
-			}
+	if ft.chunked {
+		if err := c.downloadChunkedFile(ft, opts, tracker); err != nil {
+			return err
+		}
+		return c.restoreAttributes(ft, opts)
+	}
+	var progress func(int64)
+	if tracker != nil {
+		progress = func(written int64) { tracker.set(ft, written) }
+	}
+	if err := c.fetchFile(ft.node, ft.remotePath, ft.localPath, opts, progress); err != nil {
+		return err
+	}
+	tracker.done(ft)
+
+	return c.restoreAttributes(ft, opts)
+}
+
+// fetchFile downloads the node n of the plain path remotePath to localPath.
+// An encrypted file is downloaded, and resumed, next to localPath then
+// decrypted.
+func (c *Client) fetchFile(n *node.Node, remotePath, localPath string, opts *DownloadOptions, progress func(int64)) error {
+	fileOpts := &node.DownloadFileOptions{SkipVerify: opts.SkipVerify, Progress: progress}
+	if !c.isEncrypted(remotePath) {
+		return n.DownloadFileWithOptions(localPath, fileOpts)
+	}
+	if err := n.DownloadFileWithOptions(localPath+EncryptedSuffix, fileOpts); err != nil {
+		return err
+	}
 
-			continue
+	return c.decryptFile(localPath+EncryptedSuffix, localPath)
+}
+
+// prepareDownload creates the local folders of the remote folder remotePath
+// in localPath and returns the files selected by opts.Filter, which may be
+// nil, to download. Failing to create a folder, or to decrypt a name, skips
+// its content and is returned in errs.
+func (c *Client) prepareDownload(localPath, remotePath string, opts *DownloadOptions) ([]fileTransfer, TransferErrors, error) {
+	return c.walkDownload(localPath, remotePath, opts, mkdirLocal)
+}
+
+// walkDownload calls mkdir with the local path of every folder of the remote
+// folder remotePath selected by opts.Filter, parents first, and returns the
+// files to download into localPath. A folder mkdir fails for is skipped and
+// returned in errs, as well as a name failing to decrypt.
+func (c *Client) walkDownload(localPath, remotePath string, opts *DownloadOptions, mkdir func(flp string) error) ([]fileTransfer, TransferErrors, error) {
+	ff, err := newFolderFilter(opts.Filter, func(rel string) (io.ReadCloser, error) {
+		p := path.Join(remotePath, rel)
+		if c.remoteState(p) == nil {
+			return nil, nil
 		}
+		return c.Download(p)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-		con, err := node.Download()
+	var (
+		files []fileTransfer
+		errs  TransferErrors
+		root  string
+	)
+	err = c.GetNodeTree().Walk(c.encryptPath(remotePath), func(p string, n *node.Node, err error) error {
 		if err != nil {
 			return err
 		}
-		f, err := os.Create(flp)
+		plain, err := c.decryptPath(p)
 		if err != nil {
-			log.Errorf("%s: %s", constants.ErrCreateFile, flp)
-			return constants.ErrCreateFile
+			if root == "" {
+				return err
+			}
+			errs = append(errs, &TransferError{Path: p, Err: err})
+			if n.IsDir() {
+				return node.SkipDir
+			}
+			return nil
 		}
-		log.Debugf("saving %s as %s", frp, flp)
-		_, err = io.Copy(f, con)
-		f.Close()
-		con.Close()
-		if err != nil {
-			log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
-			return err
+		p = plain
+		if root == "" {
+			root = p
+		}
+		// the names of an encrypted node are only known once decrypted.
+		if !n.IsDir() && chunkRegexp.MatchString(p) {
+			return nil
+		}
+		if n.IsDir() && p != root && !opts.Recursive {
+			return node.SkipDir
 		}
+
+		ft := fileTransfer{node: n, remotePath: p, size: n.Size()}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		switch {
+		case !n.IsDir() && strings.HasSuffix(p, ChunkedSuffix):
+			ft.remotePath = strings.TrimSuffix(p, ChunkedSuffix)
+			ft.size, ft.chunked = c.chunkedNode(ft.remotePath, n).Size(), true
+			rel = strings.TrimSuffix(rel, ChunkedSuffix)
+		case !n.IsDir() && markerMode(p) != 0 && opts.Symlinks == SymlinkSkip:
+			return nil
+		case !n.IsDir() && markerMode(p) != 0 && opts.Symlinks == SymlinkStore:
+			// a marker is restored as the link it is named after.
+			ft.mode = os.ModeSymlink
+			rel = strings.TrimSuffix(rel, SymlinkSuffix)
+		}
+		ft.localPath = path.Join(localPath, rel)
+		if p != root {
+			selected, err := ff.match(rel, n.IsDir(), ft.size, n.ModTime())
+			switch {
+			case err != nil:
+				return err
+			case !selected && n.IsDir():
+				return node.SkipDir
+			case !selected:
+				return nil
+			}
+		}
+		if !n.IsDir() {
+			files = append(files, ft)
+			return nil
+		}
+		if err := mkdir(ft.localPath); err != nil {
+			if p == root {
+				return err
+			}
+			errs = append(errs, &TransferError{Path: p, Err: err})
+			return node.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil
+	return files, errs, nil
 }
+
+func (es byTransferPath) Len() int           { return len(es) }
+func (es byTransferPath) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es byTransferPath) Less(i, j int) bool { return es[i].Path < es[j].Path }
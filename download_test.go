@@ -0,0 +1,159 @@
+package acd
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestPrepareDownload(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	tests := []struct {
+		recursive bool
+		files     []string
+		folders   []string
+	}{
+		{false, []string{"/README.md"}, nil},
+		{true, []string{"/pictures/logo.png", "/README.md"}, []string{"pictures"}},
+	}
+
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "acd-download")
+		if err != nil {
+			t.Fatal(err)
+		}
+		localPath := path.Join(dir, "root")
+		files, errs, err := c.prepareDownload(localPath, "/", &DownloadOptions{Recursive: test.recursive})
+		if err != nil || len(errs) != 0 {
+			t.Fatalf("c.prepareDownload(%q, %t) error: %v %v", localPath, test.recursive, err, errs)
+		}
+		var remotePaths []string
+		for _, f := range files {
+			remotePaths = append(remotePaths, f.remotePath)
+			if want, got := path.Join(localPath, f.remotePath), f.localPath; want != got {
+				t.Errorf("c.prepareDownload(%t) local path of %s: want %s got %s", test.recursive, f.remotePath, want, got)
+			}
+		}
+		if !reflect.DeepEqual(test.files, remotePaths) {
+			t.Errorf("c.prepareDownload(%t) files: want %v got %v", test.recursive, test.files, remotePaths)
+		}
+		for _, folder := range append(test.folders, "") {
+			if stat, err := os.Stat(path.Join(localPath, folder)); err != nil || !stat.IsDir() {
+				t.Errorf("c.prepareDownload(%t): folder %q was not created", test.recursive, folder)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPrepareDownloadSymlinks(t *testing.T) {
+	root := &node.Node{ID: "root", Root: true, Kind: "FOLDER"}
+	root.AddChild(&node.Node{ID: "docs", Name: "docs", Kind: "FOLDER"})
+	root.AddChild(&node.Node{ID: "marker", Name: "guide.md" + SymlinkSuffix, Kind: "FILE"})
+	c := &Client{
+		NodeTree: &node.Tree{Node: root},
+		config:   &Config{},
+	}
+	tests := []struct {
+		symlinks  SymlinkPolicy
+		localPath string
+		mode      os.FileMode
+	}{
+		{SymlinkFollow, "guide.md" + SymlinkSuffix, 0},
+		{SymlinkSkip, "", 0},
+		{SymlinkStore, "guide.md", os.ModeSymlink},
+	}
+
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "acd-download")
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, errs, err := c.prepareDownload(dir, "/", &DownloadOptions{Recursive: true, Symlinks: test.symlinks})
+		if err != nil || len(errs) != 0 {
+			t.Fatalf("c.prepareDownload(%d) error: %v %v", test.symlinks, err, errs)
+		}
+		var got []string
+		for _, f := range files {
+			got = append(got, strings.TrimPrefix(f.localPath, dir+"/"))
+			if f.mode != test.mode {
+				t.Errorf("c.prepareDownload(%d) mode of %s: want %s got %s", test.symlinks, f.remotePath, test.mode, f.mode)
+			}
+		}
+		var want []string
+		if test.localPath != "" {
+			want = []string{test.localPath}
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("c.prepareDownload(%d) files: want %v got %v", test.symlinks, want, got)
+		}
+		os.RemoveAll(dir)
+	}
+
+	// the files of a folder are not written where a local link points.
+	dir, err := ioutil.TempDir("", "acd-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Symlink(os.TempDir(), path.Join(dir, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	_, errs, err := c.prepareDownload(dir, "/", &DownloadOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("c.prepareDownload() error: %s", err)
+	}
+	if len(errs) != 1 || errs[0].Path != "/docs" || errs[0].Err != constants.ErrCreateFolder {
+		t.Errorf("c.prepareDownload() over a link: want [/docs: %s] got %v", constants.ErrCreateFolder, errs)
+	}
+}
+
+func TestRestoreSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acd-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(path.Join(dir, "docs/guide"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{config: &Config{PropertiesOwner: "owner"}}
+	tests := []struct {
+		name, target string
+		err          error
+	}{
+		{"docs/manual", "guide", nil},
+		{"docs/self", "./guide", nil},
+		{"docs/up", "..", constants.ErrSymlinkOutsideFolder},
+		{"docs/readme", "../README.md", constants.ErrSymlinkOutsideFolder},
+		{"docs/hidden", "guide/../../outside", constants.ErrSymlinkOutsideFolder},
+		// a link cannot be chained with another one to escape.
+		{"docs/chained", "manual/..", constants.ErrSymlinkOutsideFolder},
+		{"passwd", "/etc/passwd", constants.ErrSymlinkOutsideFolder},
+		{"docs/guide", "manual", constants.ErrFileExistsAndIsFolder},
+	}
+
+	for _, test := range tests {
+		n := &node.Node{Properties: node.Properties{"owner": {PropertySymlink: test.target}}}
+		ft := fileTransfer{node: n, localPath: path.Join(dir, test.name), mode: os.ModeSymlink}
+		if err := c.restoreSymlink(ft); err != test.err {
+			t.Errorf("c.restoreSymlink(%s -> %s): want %v got %v", test.name, test.target, test.err, err)
+		}
+		target, err := os.Readlink(ft.localPath)
+		switch {
+		case test.err == nil && target != test.target:
+			t.Errorf("c.restoreSymlink(%s): want %s got %s", test.name, test.target, target)
+		case test.err != nil && err == nil:
+			t.Errorf("c.restoreSymlink(%s -> %s): the link was created", test.name, test.target)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package acd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/acd.v0/crypt"
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+)
+
+// EncryptedSuffix is appended to the name of a local file while its encrypted
+// content is downloaded, before it is decrypted.
+const EncryptedSuffix = ".encrypted"
+
+type (
+	// EncryptionConfig configures the client-side encryption of a remote
+	// folder. The names and the contents of the files and folders under Root
+	// are encrypted by the client before they are uploaded and decrypted when
+	// they are listed or downloaded, see the crypt package.
+	EncryptionConfig struct {
+		// Root is the remote folder whose content is encrypted, e.g. "/private".
+		// The folder itself keeps its name.
+		Root string `json:"root"`
+
+		// Passphrase derives the encryption key when KeyFile is empty.
+		Passphrase string `json:"passphrase"`
+
+		// KeyFile is a file holding the encryption key, raw or hex-encoded. It
+		// must have permissions 0600 like the token file.
+		KeyFile string `json:"keyFile"`
+	}
+
+	// File is a remote file opened for random-access reads, see
+	// (*Client).OpenFile.
+	File interface {
+		io.ReadSeeker
+		io.ReaderAt
+		io.Closer
+
+		// Size returns the size of the content of the file.
+		Size() int64
+	}
+
+	sectionFile struct {
+		*io.SectionReader
+		io.Closer
+	}
+
+	readCloser struct {
+		io.Reader
+		io.Closer
+	}
+)
+
+// FindNode returns the node at path. The node of an encrypted path is a copy
+// with the plain names and sizes of the node and its children, and the node
+// of a file stored in chunks a copy of its manifest named after the file and
+// of its size. Such a node describes the file but its content must be
+// transferred with the methods of the Client.
+func (c *Client) FindNode(path string) (*node.Node, error) {
+	n, chunked, err := c.findNode(path)
+	if err != nil {
+		return nil, err
+	}
+	if chunked {
+		return c.chunkedNode(path, n), nil
+	}
+	if _, rest, ok := c.encryptedPart(path); !ok || rest == "" {
+		return n, nil
+	}
+
+	return c.decryptNode(n), nil
+}
+
+// RemoteNode returns the node of the NodeTree at path, or the manifest of a
+// file stored in chunks. Unlike the node returned by FindNode, its name is
+// the one stored on the drive, encrypted under the encrypted folder, and it
+// can be changed: renamed, moved, labeled or given properties.
+func (c *Client) RemoteNode(path string) (*node.Node, error) {
+	n, _, err := c.findNode(path)
+
+	return n, err
+}
+
+// RemotePath returns the path of path on the drive, its names being encrypted
+// under the encrypted folder.
+func (c *Client) RemotePath(path string) string {
+	return c.encryptPath(path)
+}
+
+// PathOf returns the path of the node n of the NodeTree, its names being
+// decrypted under the encrypted folder.
+func (c *Client) PathOf(n *node.Node) (string, error) {
+	p, err := c.NodeTree.PathOf(n)
+	if err != nil {
+		return "", err
+	}
+
+	return c.decryptPath(p)
+}
+
+// IsEncrypted returns whether the names and the content at path, or the
+// content of the folder path, are encrypted. A node cannot be moved or linked
+// between an encrypted path and a path which is not.
+func (c *Client) IsEncrypted(path string) bool {
+	return c.isEncrypted(path)
+}
+
+// Glob returns the paths of the nodes matching pattern, see
+// (*node.Tree).Glob. The names under the encrypted folder are matched and
+// returned decrypted.
+func (c *Client) Glob(pattern string) ([]string, error) {
+	return c.NodeTree.GlobFunc(pattern, func(dir string, n *node.Node) string {
+		if _, rest, ok := c.encryptedPart(path.Join(dir, n.Name)); ok && rest != "" {
+			if name, err := c.cipher.DecryptName(n.Name); err == nil {
+				return name
+			}
+		}
+		return n.Name
+	})
+}
+
+// Find walks the tree rooted at root and returns every node matching p, see
+// (*node.Tree).Find. Under the encrypted folder, p is given and the matches
+// are returned with the decrypted paths and nodes, like FindNode, the nodes
+// whose path cannot be decrypted being skipped.
+func (c *Client) Find(root string, p node.Predicate) ([]node.Match, error) {
+	var matches []node.Match
+	err := c.NodeTree.Walk(c.encryptPath(root), func(remotePath string, n *node.Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, rest, ok := c.encryptedPart(remotePath); ok && rest != "" {
+			if remotePath, err = c.decryptPath(remotePath); err != nil {
+				return nil
+			}
+			n = c.decryptedCopy(n)
+		}
+		if p == nil || p(remotePath, n) {
+			matches = append(matches, node.Match{Path: remotePath, Node: n})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+func newCipher(ec *EncryptionConfig) (*crypt.Cipher, error) {
+	if ec.Root == "" {
+		return nil, nil
+	}
+	ec.Root = path.Clean("/" + ec.Root)
+
+	var (
+		key []byte
+		err error
+	)
+	switch {
+	case ec.KeyFile != "":
+		if err := validateFile(ec.KeyFile, true); err != nil {
+			return nil, err
+		}
+		key, err = crypt.ReadKeyFile(ec.KeyFile)
+	case ec.Passphrase != "":
+		key, err = crypt.KeyFromPassphrase(ec.Passphrase)
+	default:
+		log.Errorf("%s: %s is encrypted but neither a passphrase nor a key file is configured", constants.ErrInvalidEncryptionKey, ec.Root)
+		return nil, constants.ErrInvalidEncryptionKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return crypt.New(key)
+}
+
+// encryptedPart splits p into the encryption root and the path under it,
+// which is empty for the root itself. ok is false if p is not encrypted.
+func (c *Client) encryptedPart(p string) (root, rest string, ok bool) {
+	if c.cipher == nil {
+		return "", "", false
+	}
+	root, p = c.config.Encryption.Root, path.Clean("/"+p)
+	if root == "/" {
+		if p == "/" {
+			return root, "", true
+		}
+		return root, p, true
+	}
+	// the names of the drive are case-insensitive.
+	if len(p) < len(root) || !strings.EqualFold(p[:len(root)], root) || (len(p) > len(root) && p[len(root)] != '/') {
+		return "", "", false
+	}
+
+	return p[:len(root)], p[len(root):], true
+}
+
+// isEncrypted returns whether the content of p is encrypted.
+func (c *Client) isEncrypted(p string) bool {
+	_, _, ok := c.encryptedPart(p)
+	return ok
+}
+
+// encryptPath returns the remote path of the plain path p.
+func (c *Client) encryptPath(p string) string {
+	root, rest, ok := c.encryptedPart(p)
+	if !ok {
+		return p
+	}
+
+	return path.Join(root, c.cipher.EncryptPath(rest))
+}
+
+// decryptPath returns the plain path of the remote path p.
+func (c *Client) decryptPath(p string) (string, error) {
+	root, rest, ok := c.encryptedPart(p)
+	if !ok {
+		return p, nil
+	}
+	rest, err := c.cipher.DecryptPath(rest)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(root, rest), nil
+}
+
+// decryptNode returns a copy of the encrypted node n, and of its children,
+// with the plain names and sizes. A name or a size which cannot be decrypted
+// is kept as is.
+func (c *Client) decryptNode(n *node.Node) *node.Node {
+	dn := c.decryptedCopy(n)
+	dn.Nodes = make(node.Nodes, 0, len(n.Nodes))
+	for _, child := range n.Nodes {
+		dn.Nodes = append(dn.Nodes, c.decryptNode(child))
+	}
+
+	return dn
+}
+
+// decryptedCopy returns a copy of the encrypted node n with the plain name
+// and size, sharing the children of n.
+func (c *Client) decryptedCopy(n *node.Node) *node.Node {
+	dn := *n
+	if name, err := c.cipher.DecryptName(n.Name); err == nil {
+		dn.Name = name
+	}
+	if n.IsFile() {
+		if size, err := crypt.DecryptedSize(n.Size()); err == nil {
+			dn.ContentProperties.Size = uint64(size)
+		}
+	}
+
+	return &dn
+}
+
+// encryptReader returns a reader of the content of r as stored at the plain
+// path p, encrypted if p is.
+func (c *Client) encryptReader(p string, r io.Reader) (io.Reader, error) {
+	if !c.isEncrypted(p) {
+		return r, nil
+	}
+
+	return c.cipher.NewEncryptingReader(r)
+}
+
+// contentMD5 returns the MD5 of the content of r as stored in the existing
+// file ft.node. An encrypted content is encrypted with the salt of the
+// existing file so that the same content gives the same MD5. The MD5 of a
+// chunked file is the MD5 of its plain content, see chunkManifest.
+func (c *Client) contentMD5(ft fileTransfer, r io.Reader) (string, error) {
+	if c.isEncrypted(ft.remotePath) && !ft.chunked {
+		f, err := ft.node.Open()
+		if err != nil {
+			return "", err
+		}
+		header := make([]byte, crypt.HeaderSize)
+		_, err = f.ReadAt(header, 0)
+		f.Close()
+		if err != nil {
+			log.Errorf("%s: reading the header: %s", constants.ErrInvalidEncryptedContent, err)
+			return "", constants.ErrInvalidEncryptedContent
+		}
+		if r, err = c.cipher.NewEncryptingReaderWithHeader(r, header); err != nil {
+			return "", err
+		}
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		log.Errorf("%s: %s", constants.ErrReadingFileContents, err)
+		return "", constants.ErrReadingFileContents
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// decryptFile decrypts the file encryptedPath into localPath, followed by
+// node.PartialSuffix until it is complete, then removes it. localPath is left
+// untouched if the content cannot be decrypted.
+func (c *Client) decryptFile(encryptedPath, localPath string) error {
+	src, err := os.Open(encryptedPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrOpenFile, err)
+		return constants.ErrOpenFile
+	}
+	defer src.Close()
+	r, err := c.cipher.NewDecryptingReader(src)
+	if err != nil {
+		return err
+	}
+
+	// the plaintext is only renamed to localPath once complete.
+	partialPath := localPath + node.PartialSuffix
+	dst, err := os.Create(partialPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return constants.ErrCreateFile
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		os.Remove(partialPath)
+		if err == constants.ErrInvalidEncryptedContent {
+			return err
+		}
+		log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
+		return constants.ErrWritingFileContents
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(partialPath)
+		log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
+		return constants.ErrWritingFileContents
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		os.Remove(partialPath)
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return constants.ErrCreateFile
+	}
+
+	return os.Remove(encryptedPath)
+}
@@ -0,0 +1,140 @@
+package acd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/acd.v0/crypt"
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestEncryptPath(t *testing.T) {
+	cipher, err := crypt.New(bytes.Repeat([]byte{42}, crypt.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		config: &Config{Encryption: EncryptionConfig{Root: "/private"}},
+		cipher: cipher,
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"/README.md", "/README.md"},
+		{"/privateer/a", "/privateer/a"},
+		{"/private", "/private"},
+		{"/private/a/b.txt", path.Join("/private", cipher.EncryptName("a"), cipher.EncryptName("b.txt"))},
+		{"/Private/a", path.Join("/Private", cipher.EncryptName("a"))},
+	}
+
+	for _, test := range tests {
+		got := c.encryptPath(test.path)
+		if got != test.want {
+			t.Errorf("c.encryptPath(%q): want %q got %q", test.path, test.want, got)
+		}
+		if plain, err := c.decryptPath(got); err != nil || plain != test.path {
+			t.Errorf("c.decryptPath(%q): want %q got %q %v", got, test.path, plain, err)
+		}
+	}
+}
+
+func TestEncryptedGlobAndFind(t *testing.T) {
+	cipher, err := crypt.New(bytes.Repeat([]byte{42}, crypt.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		root    = &node.Node{ID: "root", Root: true, Kind: "FOLDER"}
+		private = &node.Node{ID: "private", Name: "private", Kind: "FOLDER"}
+		docs    = &node.Node{ID: "docs", Name: cipher.EncryptName("docs"), Kind: "FOLDER"}
+	)
+	root.AddChild(&node.Node{ID: "readme", Name: "README.md", Kind: "FILE"})
+	root.AddChild(private)
+	private.AddChild(docs)
+	docs.AddChild(&node.Node{ID: "a", Name: cipher.EncryptName("a.txt"), Kind: "FILE"})
+	docs.AddChild(&node.Node{ID: "b", Name: cipher.EncryptName("b.md"), Kind: "FILE"})
+	c := &Client{
+		NodeTree: &node.Tree{Node: root},
+		config:   &Config{Encryption: EncryptionConfig{Root: "/private"}},
+		cipher:   cipher,
+	}
+
+	globs := map[string][]string{
+		"/*.md":             []string{"/README.md"},
+		"/private/*/*.txt":  []string{"/private/docs/a.txt"},
+		"/private/**/*.md":  []string{"/private/docs/b.md"},
+		"/private/docs/*.x": nil,
+	}
+	for pattern, want := range globs {
+		got, err := c.Glob(pattern)
+		if err != nil {
+			t.Fatalf("c.Glob(%q) error: %s", pattern, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("c.Glob(%q): want %v got %v", pattern, want, got)
+		}
+	}
+
+	matches, err := c.Find("/", node.NameMatches("*.txt"))
+	if err != nil {
+		t.Fatalf("c.Find() error: %s", err)
+	}
+	if len(matches) != 1 || matches[0].Path != "/private/docs/a.txt" || matches[0].Node.Name != "a.txt" || matches[0].Node.ID != "a" {
+		t.Errorf("c.Find(%q, -name *.txt): want /private/docs/a.txt got %v", "/", matches)
+	}
+}
+
+func TestDecryptFile(t *testing.T) {
+	cipher, err := crypt.New(bytes.Repeat([]byte{42}, crypt.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{cipher: cipher}
+	dir, err := ioutil.TempDir("", "acd-decrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r, err := cipher.NewEncryptingReader(strings.NewReader("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		encrypted []byte
+		want      string
+		err       error
+	}{
+		// the existing file is kept if the content was tampered with.
+		{append(encrypted[:len(encrypted)-1:len(encrypted)-1], encrypted[len(encrypted)-1]^1), "existing", constants.ErrInvalidEncryptedContent},
+		{encrypted, "content", nil},
+	}
+	localPath := path.Join(dir, "file")
+	for i, test := range tests {
+		if err := ioutil.WriteFile(localPath, []byte("existing"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(localPath+EncryptedSuffix, test.encrypted, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.decryptFile(localPath+EncryptedSuffix, localPath); err != test.err {
+			t.Errorf("#%d: c.decryptFile(): want %v got %v", i, test.err, err)
+		}
+		if got, err := ioutil.ReadFile(localPath); err != nil || string(got) != test.want {
+			t.Errorf("#%d: c.decryptFile() content: want %q got %q %v", i, test.want, got, err)
+		}
+		if _, err := os.Stat(localPath + node.PartialSuffix); !os.IsNotExist(err) {
+			t.Errorf("#%d: c.decryptFile() left %s", i, localPath+node.PartialSuffix)
+		}
+	}
+}
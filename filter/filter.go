@@ -0,0 +1,247 @@
+// Package filter selects the files of a folder transfer with gitignore-style
+// patterns, the ignore files found in the folders, and limits on the size and
+// the age of the files.
+//
+// A pattern is matched against the path of a file relative to the folder of
+// the rule, its elements being matched with path.Match. An element consisting
+// only of ** matches zero or more folders. A pattern without a slash matches
+// a name in any folder, a pattern ending with a slash only matches folders,
+// and a pattern starting with ! includes again what an earlier rule excluded.
+// The last rule matching a path wins, and the content of an excluded folder
+// is never walked.
+package filter // import "gopkg.in/acd.v0/filter"
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// DefaultIgnoreFile is the name of the ignore files read by the CLI.
+const DefaultIgnoreFile = ".acdignore"
+
+// Filter selects the files and the folders of a folder transfer. The zero
+// Filter selects everything. A Filter must not be modified once in use.
+type Filter struct {
+	// IgnoreFile, unless empty, is the name of the files whose rules, in
+	// gitignore syntax, apply to the folder they are in and its sub-folders.
+	IgnoreFile string
+
+	// MinSize and MaxSize, unless zero, are the sizes of the smallest and the
+	// largest files selected.
+	MinSize, MaxSize int64
+
+	// MinAge and MaxAge, unless zero, are the ages of the most and the least
+	// recently modified files selected.
+	MinAge, MaxAge time.Duration
+
+	includes []rule
+	rules    []rule
+}
+
+type rule struct {
+	// dir is the folder the pattern is relative to, "." for the root.
+	dir     string
+	parts   []string
+	negate  bool
+	dirOnly bool
+}
+
+// Include restricts the selected files to the files matching one of the
+// included patterns. The folders are walked regardless.
+func (f *Filter) Include(pattern string) error {
+	r, err := parseRule(".", pattern)
+	if err != nil {
+		return err
+	}
+	f.includes = append(f.includes, r)
+
+	return nil
+}
+
+// Exclude adds the gitignore rule line, which excludes the paths matching it
+// or includes them again if it starts with !.
+func (f *Filter) Exclude(line string) error {
+	r, err := parseRule(".", line)
+	if err != nil {
+		return err
+	}
+	f.rules = append(f.rules, r)
+
+	return nil
+}
+
+// ReadRules adds the rules of r, one per line in gitignore syntax. The blank
+// lines and the lines starting with # are ignored.
+func (f *Filter) ReadRules(r io.Reader) error {
+	rules, err := readRules(".", r)
+	if err != nil {
+		return err
+	}
+	f.rules = append(f.rules, rules...)
+
+	return nil
+}
+
+// ReadRulesFile adds the rules of the file name, see ReadRules.
+func (f *Filter) ReadRulesFile(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrOpenFile, err)
+		return constants.ErrOpenFile
+	}
+	defer file.Close()
+
+	return f.ReadRules(file)
+}
+
+// WithRules returns a copy of f applying the rules of r, the ignore file of
+// the folder dir, to dir and its sub-folders. dir is relative to the root of
+// the transfer, "." being the root.
+func (f *Filter) WithRules(dir string, r io.Reader) (*Filter, error) {
+	rules, err := readRules(path.Clean(dir), r)
+	if err != nil {
+		return nil, err
+	}
+	cf := *f
+	cf.rules = append(append([]rule(nil), f.rules...), rules...)
+
+	return &cf, nil
+}
+
+// Match returns whether the file, or the folder if isDir is set, of the path
+// rel relative to the root of the transfer is selected. The size and the age
+// limits only apply to files.
+func (f *Filter) Match(rel string, isDir bool, size int64, modTime time.Time) bool {
+	rel = path.Clean(rel)
+	excluded := false
+	for _, r := range f.rules {
+		if r.match(rel, isDir) {
+			excluded = !r.negate
+		}
+	}
+	if excluded || isDir {
+		return !excluded
+	}
+
+	if len(f.includes) > 0 {
+		included := false
+		for _, r := range f.includes {
+			if r.match(rel, isDir) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if (f.MinSize > 0 && size < f.MinSize) || (f.MaxSize > 0 && size > f.MaxSize) {
+		return false
+	}
+	age := time.Since(modTime)
+	if (f.MinAge > 0 && age < f.MinAge) || (f.MaxAge > 0 && age > f.MaxAge) {
+		return false
+	}
+
+	return true
+}
+
+// readRules returns the rules of r relative to dir.
+func readRules(dir string, r io.Reader) ([]rule, error) {
+	var rules []rule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseRule(dir, line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Errorf("%s: %s", constants.ErrReadingFileContents, err)
+		return nil, constants.ErrReadingFileContents
+	}
+
+	return rules, nil
+}
+
+// parseRule parses the gitignore rule line relative to dir.
+func parseRule(dir, line string) (rule, error) {
+	r := rule{dir: dir}
+	pattern := line
+	if strings.HasPrefix(pattern, "!") {
+		r.negate, pattern = true, pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly, pattern = true, strings.TrimRight(pattern, "/")
+	}
+	if strings.TrimLeft(pattern, "/") == "" {
+		log.Errorf("%s: %q", constants.ErrInvalidGlobPattern, line)
+		return r, constants.ErrInvalidGlobPattern
+	}
+	// a pattern without a slash matches in any folder.
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+	r.parts = strings.Split(pattern, "/")
+	for _, part := range r.parts {
+		if _, err := path.Match(part, ""); err != nil {
+			log.Errorf("%s: %q", constants.ErrInvalidGlobPattern, line)
+			return r, constants.ErrInvalidGlobPattern
+		}
+	}
+
+	return r, nil
+}
+
+// match returns whether the rule matches the path rel, relative to the root
+// of the transfer.
+func (r rule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.dir != "." {
+		if !strings.HasPrefix(rel, r.dir+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, r.dir+"/")
+	}
+
+	return matchParts(r.parts, strings.Split(rel, "/"))
+}
+
+// matchParts returns whether the elements of a path match the elements of a
+// pattern, ** matching zero or more elements.
+func matchParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
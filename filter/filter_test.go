@@ -0,0 +1,91 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	f := &Filter{}
+	if err := f.ReadRules(strings.NewReader("# build output\n*.o\nbuild/\n/secret.txt\ndocs/**/*.tmp\n!keep.o\n")); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := f.WithRules("src", strings.NewReader("*.log\n!/main.o\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		f     *Filter
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{f, "main.c", false, true},
+		{f, "main.o", false, false},
+		{f, "src/lib/util.o", false, false},
+		{f, "keep.o", false, true},
+		{f, "build", true, false},
+		{f, "build", false, true},
+		{f, "secret.txt", false, false},
+		{f, "src/secret.txt", false, true},
+		{f, "docs/a/b/c.tmp", false, false},
+		{f, "docs/c.tmp", false, false},
+		{f, "c.tmp", false, true},
+		{f, "src/debug.log", false, true},
+		{sub, "src/debug.log", false, false},
+		{sub, "src/lib/debug.log", false, false},
+		{sub, "debug.log", false, true},
+		{sub, "src/main.o", false, true},
+		{sub, "src/lib/main.o", false, false},
+	}
+
+	for _, test := range tests {
+		if got := test.f.Match(test.rel, test.isDir, 0, time.Time{}); got != test.want {
+			t.Errorf("Match(%q, %t): want %t got %t", test.rel, test.isDir, test.want, got)
+		}
+	}
+}
+
+func TestMatchIncludeAndLimits(t *testing.T) {
+	f := &Filter{
+		MinSize: 10,
+		MaxSize: 100,
+		MaxAge:  48 * time.Hour,
+	}
+	if err := f.Include("*.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		rel     string
+		isDir   bool
+		size    int64
+		modTime time.Time
+		want    bool
+	}{
+		{"photo.jpg", false, 50, now, true},
+		{"album/photo.jpg", false, 50, now, true},
+		{"photo.png", false, 50, now, false},
+		{"album", true, 0, now, true},
+		{"photo.jpg", false, 5, now, false},
+		{"photo.jpg", false, 500, now, false},
+		{"photo.jpg", false, 50, now.Add(-72 * time.Hour), false},
+	}
+
+	for _, test := range tests {
+		if got := f.Match(test.rel, test.isDir, test.size, test.modTime); got != test.want {
+			t.Errorf("Match(%q, %t, %d): want %t got %t", test.rel, test.isDir, test.size, test.want, got)
+		}
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"[a-", "/", "!"} {
+		if err := (&Filter{}).Exclude(pattern); err == nil {
+			t.Errorf("Exclude(%q): want an error got nil", pattern)
+		}
+	}
+}
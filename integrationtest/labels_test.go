@@ -0,0 +1,56 @@
+package integrationtest
+
+import (
+	"os"
+	"testing"
+
+	"gopkg.in/acd.v0"
+)
+
+func TestUploadWithLabels(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	needCleaning = true
+
+	var (
+		readmeFile       = "fixtures/README"
+		remoteReadmeFile = remotePath("fixtures/labels/README")
+	)
+
+	c, err := newCachedClient(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FetchNodeTree(); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(readmeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if err := c.UploadWithOptions(remoteReadmeFile, in, &acd.UploadOptions{Labels: []string{"integration"}}); err != nil {
+		t.Fatalf("error uploading %s to %s: %s", readmeFile, remoteReadmeFile, err)
+	}
+	node, err := c.NodeTree.FindNode(remoteReadmeFile)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteReadmeFile, err)
+	}
+	if !node.HasLabel("integration") {
+		t.Errorf("node.Labels: want integration got %v", node.Labels)
+	}
+	if nodes := c.NodeTree.FindByLabel("integration"); len(nodes) != 1 || nodes[0].ID != node.ID {
+		t.Errorf("c.NodeTree.FindByLabel(%q): want [%s] got %v", "integration", node.ID, nodes)
+	}
+
+	if err := node.AddLabels("second"); err != nil {
+		t.Fatalf("node.AddLabels(%q) error: %s", "second", err)
+	}
+	if err := node.RemoveLabels("integration"); err != nil {
+		t.Fatalf("node.RemoveLabels(%q) error: %s", "integration", err)
+	}
+	if node.HasLabel("integration") || !node.HasLabel("second") {
+		t.Errorf("node.Labels: want [second] got %v", node.Labels)
+	}
+}
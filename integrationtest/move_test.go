@@ -0,0 +1,80 @@
+package integrationtest
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMoveAndRename(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	needCleaning = true
+
+	var (
+		readmeFile        = "fixtures/README"
+		remoteReadmeFile  = remotePath("fixtures/movefolder/src/README")
+		remoteSrcFolder   = remotePath("fixtures/movefolder/src")
+		remoteDestFolder  = remotePath("fixtures/movefolder/dest")
+		remoteMovedReadme = remotePath("fixtures/movefolder/dest/README.moved")
+	)
+
+	c, err := newCachedClient(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FetchNodeTree(); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(readmeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if err := c.Upload(remoteReadmeFile, false, in); err != nil {
+		t.Fatalf("error uploading %s to %s: %s", readmeFile, remoteReadmeFile, err)
+	}
+	node, err := c.NodeTree.FindNode(remoteReadmeFile)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteReadmeFile, err)
+	}
+	src, err := c.NodeTree.FindNode(remoteSrcFolder)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteSrcFolder, err)
+	}
+	dest, err := c.NodeTree.MkdirAll(remoteDestFolder)
+	if err != nil {
+		t.Fatalf("c.NodeTree.MkdirAll(%q) error: %s", remoteDestFolder, err)
+	}
+
+	if err := node.Rename("README.moved"); err != nil {
+		t.Fatalf("node.Rename(%q) error: %s", "README.moved", err)
+	}
+	if err := node.Move(src, dest); err != nil {
+		t.Fatalf("node.Move(%q, %q) error: %s", remoteSrcFolder, remoteDestFolder, err)
+	}
+
+	// test the NodeTree is updated
+	if _, err := c.NodeTree.FindNode(remoteReadmeFile); err == nil {
+		t.Errorf("c.NodeTree.FindNode(%q): want an error after moving it", remoteReadmeFile)
+	}
+	moved, err := c.NodeTree.FindNode(remoteMovedReadme)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteMovedReadme, err)
+	}
+	if want, got := node.ID, moved.ID; want != got {
+		t.Errorf("c.NodeTree.FindNode(%q).ID: want %s got %s", remoteMovedReadme, want, got)
+	}
+
+	// check the server agrees
+	uc, err := newUncachedClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.FetchNodeTree(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.NodeTree.FindNode(remoteMovedReadme); err != nil {
+		t.Errorf("uc.NodeTree.FindNode(%q) error: %s", remoteMovedReadme, err)
+	}
+}
@@ -0,0 +1,72 @@
+package integrationtest
+
+import (
+	"os"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func TestAddRemoveParent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	needCleaning = true
+
+	var (
+		readmeFile         = "fixtures/README"
+		remoteReadmeFile   = remotePath("fixtures/linkfolder/originals/README")
+		remoteOriginals    = remotePath("fixtures/linkfolder/originals")
+		remoteAlbum        = remotePath("fixtures/linkfolder/album")
+		remoteLinkedReadme = remotePath("fixtures/linkfolder/album/README")
+	)
+
+	c, err := newCachedClient(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FetchNodeTree(); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(readmeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if err := c.Upload(remoteReadmeFile, false, in); err != nil {
+		t.Fatalf("error uploading %s to %s: %s", readmeFile, remoteReadmeFile, err)
+	}
+	node, err := c.NodeTree.FindNode(remoteReadmeFile)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteReadmeFile, err)
+	}
+	originals, err := c.NodeTree.FindNode(remoteOriginals)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteOriginals, err)
+	}
+	album, err := c.NodeTree.MkdirAll(remoteAlbum)
+	if err != nil {
+		t.Fatalf("c.NodeTree.MkdirAll(%q) error: %s", remoteAlbum, err)
+	}
+
+	if err := node.AddParent(album); err != nil {
+		t.Fatalf("node.AddParent(%q) error: %s", remoteAlbum, err)
+	}
+	linked, err := c.NodeTree.FindNode(remoteLinkedReadme)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteLinkedReadme, err)
+	}
+	if want, got := node.ID, linked.ID; want != got {
+		t.Errorf("c.NodeTree.FindNode(%q).ID: want %s got %s", remoteLinkedReadme, want, got)
+	}
+
+	if err := node.RemoveParent(originals); err != nil {
+		t.Fatalf("node.RemoveParent(%q) error: %s", remoteOriginals, err)
+	}
+	if _, err := c.NodeTree.FindNode(remoteReadmeFile); err == nil {
+		t.Errorf("c.NodeTree.FindNode(%q): want an error after removing the parent", remoteReadmeFile)
+	}
+	if want, got := constants.ErrCannotRemoveLastParent, node.RemoveParent(album); want != got {
+		t.Errorf("node.RemoveParent(%q): want %s got %s", remoteAlbum, want, got)
+	}
+}
@@ -0,0 +1,74 @@
+package integrationtest
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTrashRestore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	needCleaning = true
+
+	var (
+		readmeFile       = "fixtures/README"
+		remoteReadmeFile = remotePath("fixtures/trashfolder/README")
+	)
+
+	c, err := newCachedClient(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FetchNodeTree(); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(readmeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if err := c.Upload(remoteReadmeFile, false, in); err != nil {
+		t.Fatalf("error uploading %s to %s: %s", readmeFile, remoteReadmeFile, err)
+	}
+	node, err := c.NodeTree.FindNode(remoteReadmeFile)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) error: %s", remoteReadmeFile, err)
+	}
+
+	// trash the file and find it in the trash with its original path
+	if err := c.NodeTree.RemoveNode(node); err != nil {
+		t.Fatalf("c.NodeTree.RemoveNode(%q) error: %s", remoteReadmeFile, err)
+	}
+	if _, err := c.NodeTree.FindNode(remoteReadmeFile); err == nil {
+		t.Errorf("c.NodeTree.FindNode(%q): want an error after removing it", remoteReadmeFile)
+	}
+	tns, err := c.NodeTree.ListTrash()
+	if err != nil {
+		t.Fatalf("c.NodeTree.ListTrash() error: %s", err)
+	}
+	var found bool
+	for _, tn := range tns {
+		if tn.ID == node.ID {
+			found = true
+			if want, got := remoteReadmeFile, tn.OriginalPath; want != got {
+				t.Errorf("c.NodeTree.ListTrash() OriginalPath: want %s got %s", want, got)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("c.NodeTree.ListTrash(): %q not found in the trash", remoteReadmeFile)
+	}
+
+	// restore it and make sure the tree has it back
+	if err := c.NodeTree.Restore(node, nil); err != nil {
+		t.Fatalf("c.NodeTree.Restore(%q) error: %s", remoteReadmeFile, err)
+	}
+	restored, err := c.NodeTree.FindNode(remoteReadmeFile)
+	if err != nil {
+		t.Fatalf("c.NodeTree.FindNode(%q) after restore error: %s", remoteReadmeFile, err)
+	}
+	if want, got := node.ID, restored.ID; want != got {
+		t.Errorf("c.NodeTree.FindNode(%q).ID after restore: want %s got %s", remoteReadmeFile, want, got)
+	}
+}
@@ -62,8 +62,18 @@ var (
 	ErrCreatingWriterFromFile = errors.New("error creating a writer from a file")
 	// ErrWritingFileContents is returned if an error happens when writing the file contents
 	ErrWritingFileContents = errors.New("error writing the file contents")
+	// ErrReadingFileContents is returned if an error happens when reading the file contents
+	ErrReadingFileContents = errors.New("error reading the file contents")
 	// ErrNoContentsToUpload is returned if the reader does not even have one byte.
 	ErrNoContentsToUpload = errors.New("reader has not contents to upload")
+	// ErrNoPropertiesOwner is returned if the properties of a node must be
+	// written but Config.PropertiesOwner is not set.
+	ErrNoPropertiesOwner = errors.New("the owner of the properties is not configured")
+	// ErrInvalidPlan is returned if a transfer plan cannot be read or applied.
+	ErrInvalidPlan = errors.New("invalid transfer plan")
+	// ErrPlanOutdated is returned if the remote nodes a transfer plan relies on
+	// changed since it was made.
+	ErrPlanOutdated = errors.New("the remote state changed since the plan was made")
 
 	// JSON errors
 
@@ -92,6 +102,47 @@ var (
 	// ErrCannotCreateANodeUnderAFile is returned if you attempt to create a
 	// folder/file under an existing file.
 	ErrCannotCreateANodeUnderAFile = errors.New("cannot create a node under a file")
+	// ErrInvalidNodeName is returned if a node name is empty or contains a
+	// slash.
+	ErrInvalidNodeName = errors.New("invalid node name")
+	// ErrCannotMoveIntoItself is returned if you attempt to move a folder into
+	// itself or one of its sub-folders.
+	ErrCannotMoveIntoItself = errors.New("cannot move a folder into itself")
+	// ErrCannotRemoveLastParent is returned if you attempt to remove the only
+	// parent of a node.
+	ErrCannotRemoveLastParent = errors.New("cannot remove the last parent of a node")
+	// ErrInvalidGlobPattern is returned if a glob pattern is malformed.
+	ErrInvalidGlobPattern = errors.New("invalid glob pattern")
+	// ErrInvalidQuery is returned if a query expression cannot be parsed.
+	ErrInvalidQuery = errors.New("invalid query expression")
+	// ErrInvalidSize is returned if a size cannot be parsed.
+	ErrInvalidSize = errors.New("invalid size")
+	// ErrNoTempLink is returned if the server did not return a temporary link
+	// for a node.
+	ErrNoTempLink = errors.New("no temporary link returned for the node")
+	// ErrInvalidSeek is returned if you attempt to seek before the start of a
+	// node or with an invalid whence.
+	ErrInvalidSeek = errors.New("invalid seek")
+	// ErrFileClosed is returned if you attempt to read from a closed node.
+	ErrFileClosed = errors.New("read from a closed node")
+	// ErrMD5Mismatch is returned if the MD5 of the content of a node does not
+	// match the MD5 of the content sent or received.
+	ErrMD5Mismatch = errors.New("the MD5 of the content does not match")
+	// ErrSizeMismatch is returned if the size of the content received does not
+	// match the size of the node.
+	ErrSizeMismatch = errors.New("the size of the content does not match")
+	// ErrInvalidEncryptionKey is returned if no encryption key can be derived
+	// from the configured passphrase or key file.
+	ErrInvalidEncryptionKey = errors.New("invalid encryption key")
+	// ErrInvalidEncryptedContent is returned if an encrypted content is
+	// malformed, truncated or fails authentication.
+	ErrInvalidEncryptedContent = errors.New("invalid encrypted content")
+	// ErrInvalidEncryptedName is returned if an encrypted name is malformed or
+	// fails authentication.
+	ErrInvalidEncryptedName = errors.New("invalid encrypted name")
+	// ErrNotPlainFile is returned if the content of a file, encrypted or
+	// stored in chunks, cannot be read as stored.
+	ErrNotPlainFile = errors.New("the file is encrypted or stored in chunks")
 
 	// URL errors
 
@@ -110,6 +161,13 @@ var (
 	ErrCreateFolder = errors.New("error creating a folder")
 	// ErrFileExists is returned if the file already exists (on the server or locally).
 	ErrFileExists = errors.New("the file already exists")
+	// ErrSpecialFile is returned if a file is neither a regular file, a folder
+	// nor a symbolic link, e.g. a socket, a device or a named pipe.
+	ErrSpecialFile = errors.New("the file is not a regular file")
+	// ErrSymlinkOutsideFolder is returned if the target of a symbolic link to
+	// restore is absolute or has a .. element, it could point outside of the
+	// folder it is downloaded to.
+	ErrSymlinkOutsideFolder = errors.New("the symbolic link points outside of the downloaded folder")
 	// ErrFileNotFound is returned if no such file or directory.
 	ErrFileNotFound = errors.New("no such file or directory")
 	// ErrPathIsNotFolder is returned if the path is not a folder.
@@ -10,9 +10,11 @@ import (
 // to the caller to differentiate between a file, a folder or an asset by using
 // (*node.Node).IsFile(), (*node.Node).IsDir() and/or (*node.Node).IsAsset().
 // A dir has sub-nodes accessible via (*node.Node).Nodes, you do not need to
-// call this this function for every sub-node.
+// call this this function for every sub-node. The nodes under an encrypted
+// folder are copies, see (*Client).FindNode, and a file stored in chunks is
+// listed as one file of the size of its chunks.
 func (c *Client) List(path string) (node.Nodes, error) {
-	rootNode, err := c.GetNodeTree().FindNode(path)
+	rootNode, err := c.GetNodeTree().FindNode(c.encryptPath(path))
 	if err != nil {
 		return nil, err
 	}
@@ -21,5 +23,14 @@ func (c *Client) List(path string) (node.Nodes, error) {
 		return nil, constants.ErrPathIsNotFolder
 	}
 
-	return rootNode.Nodes, nil
+	nodes := rootNode.Nodes
+	if c.isEncrypted(path) {
+		// the nodes under an encrypted folder are listed with their plain names.
+		nodes = make(node.Nodes, 0, len(rootNode.Nodes))
+		for _, n := range rootNode.Nodes {
+			nodes = append(nodes, c.decryptNode(n))
+		}
+	}
+
+	return mergeChunks(nodes), nil
 }
@@ -0,0 +1,37 @@
+package node
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+// testClient is a client talking to a test HTTP server, both the metadata
+// and the content URLs point to it.
+type testClient struct {
+	*httptest.Server
+	tree     *Tree
+	requests int
+}
+
+func newTestClient(handler http.HandlerFunc) *testClient {
+	return &testClient{Server: httptest.NewServer(handler)}
+}
+
+func (c *testClient) GetMetadataURL(path string) string { return c.URL + "/" + path }
+func (c *testClient) GetContentURL(path string) string  { return c.URL + "/" + path }
+func (c *testClient) GetNodeTree() *Tree                { return c.tree }
+
+func (c *testClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultClient.Do(req)
+}
+
+func (c *testClient) CheckResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	res.Body.Close()
+
+	return fmt.Errorf("unexpected status %s", res.Status)
+}
@@ -0,0 +1,198 @@
+package node
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+type (
+	// Duplicates is a set of distinct file nodes sharing the same content.
+	Duplicates struct {
+		MD5     string
+		Size    uint64
+		Matches []Match
+	}
+
+	// KeepRule chooses the copy to keep among a set of duplicates and returns
+	// its index in matches.
+	KeepRule func(matches []Match) int
+
+	bySize []*Duplicates
+)
+
+// Reclaimable returns the number of bytes freed by keeping a single copy.
+func (d *Duplicates) Reclaimable() uint64 {
+	return d.Size * uint64(len(d.Matches)-1)
+}
+
+// FindDuplicates returns the sets of files under root having the same MD5 and
+// the same size, the biggest sets first. A node linked in several folders is
+// not a duplicate of itself and is reported once, under its first path.
+func (nt *Tree) FindDuplicates(root string) ([]*Duplicates, error) {
+	var (
+		sets []*Duplicates
+		keys = make(map[string]*Duplicates)
+		seen = make(map[string]bool)
+	)
+
+	err := nt.Walk(root, func(p string, n *Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if !n.IsFile() || n.ContentProperties.MD5 == "" || seen[n.ID] {
+			return nil
+		}
+		seen[n.ID] = true
+
+		key := strings.ToLower(n.ContentProperties.MD5) + "/" + strconv.FormatUint(n.ContentProperties.Size, 10)
+		d, found := keys[key]
+		if !found {
+			d = &Duplicates{
+				MD5:  strings.ToLower(n.ContentProperties.MD5),
+				Size: n.ContentProperties.Size,
+			}
+			keys[key] = d
+			sets = append(sets, d)
+		}
+		d.Matches = append(d.Matches, Match{Path: p, Node: n})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var dups []*Duplicates
+	for _, d := range sets {
+		if len(d.Matches) > 1 {
+			dups = append(dups, d)
+		}
+	}
+	sort.Stable(bySize(dups))
+
+	return dups, nil
+}
+
+// Dedupe keeps the copy of d chosen by keep and trashes the other copies. If
+// link is true, the kept node is added as an extra parent to the folders of
+// the trashed copies so every folder still has the content, under the name of
+// the kept node. Nothing is trashed unless every folder can take the kept
+// node. It returns the kept copy and the copies trashed, before the error if
+// any.
+func (nt *Tree) Dedupe(d *Duplicates, keep KeepRule, link bool) (Match, []Match, error) {
+	var (
+		kept    = d.Matches[keep(d.Matches)]
+		copies  = make(map[*Node]bool)
+		parents []*Node
+		seen    = make(map[string]bool)
+		trashed []Match
+	)
+	for _, m := range d.Matches {
+		if m.Node != kept.Node {
+			copies[m.Node] = true
+		}
+	}
+	for _, m := range d.Matches {
+		if !link || !copies[m.Node] {
+			continue
+		}
+		for _, parentID := range m.Node.Parents {
+			parent, err := nt.FindByID(parentID)
+			if err != nil || seen[parentID] || kept.Node.hasParent(parent) {
+				continue
+			}
+			seen[parentID] = true
+			if err := canLink(parent, kept.Node, copies); err != nil {
+				return kept, nil, err
+			}
+			parents = append(parents, parent)
+		}
+	}
+
+	// the copies must be trashed first or a folder would have two nodes with
+	// the same name.
+	for _, m := range d.Matches {
+		if !copies[m.Node] {
+			continue
+		}
+		if err := nt.RemoveNode(m.Node); err != nil {
+			return kept, trashed, err
+		}
+		trashed = append(trashed, m)
+	}
+	for _, parent := range parents {
+		if err := kept.Node.AddParent(parent); err != nil {
+			return kept, trashed, err
+		}
+	}
+
+	return kept, trashed, nil
+}
+
+// canLink returns the error (*Node).AddParent would return for adding parent
+// to the parents of n once the nodes of trashed are gone from it.
+func canLink(parent, n *Node, trashed map[*Node]bool) error {
+	if !parent.IsDir() {
+		log.Errorf("%s: %s", constants.ErrPathIsNotFolder, parent.Name)
+		return constants.ErrPathIsNotFolder
+	}
+	for _, c := range parent.Nodes {
+		if c != n && !trashed[c] && strings.EqualFold(c.Name, n.Name) {
+			log.Errorf("%s: %s", constants.ErrFileExists, path.Join(parent.Name, n.Name))
+			return constants.ErrFileExists
+		}
+	}
+
+	return nil
+}
+
+// KeepOldest keeps the copy created first.
+func KeepOldest(matches []Match) int {
+	keep := 0
+	for i, m := range matches {
+		if m.Node.CreationDate.Before(matches[keep].Node.CreationDate) {
+			keep = i
+		}
+	}
+
+	return keep
+}
+
+// KeepShortestPath keeps the copy with the shortest path.
+func KeepShortestPath(matches []Match) int {
+	keep := 0
+	for i, m := range matches {
+		if len(m.Path) < len(matches[keep].Path) {
+			keep = i
+		}
+	}
+
+	return keep
+}
+
+// KeepInFolder returns a KeepRule keeping the first copy found under the
+// folder prefix, and falling back to the rule fallback if there is none.
+func KeepInFolder(prefix string, fallback KeepRule) KeepRule {
+	prefix = strings.ToLower(cleanPath(prefix))
+	return func(matches []Match) int {
+		for i, m := range matches {
+			dir := strings.ToLower(path.Dir(m.Path))
+			if dir == prefix || strings.HasPrefix(dir, prefix+"/") || prefix == "/" {
+				return i
+			}
+		}
+
+		return fallback(matches)
+	}
+}
+
+func (ds bySize) Len() int      { return len(ds) }
+func (ds bySize) Swap(i, j int) { ds[i], ds[j] = ds[j], ds[i] }
+func (ds bySize) Less(i, j int) bool {
+	return ds[i].Reclaimable() > ds[j].Reclaimable()
+}
@@ -0,0 +1,113 @@
+package node
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	var (
+		content = ContentProperties{Size: 10, MD5: "aaa"}
+		a       = &Node{ID: "a", Name: "a.jpg", Kind: "FILE", CreationDate: time.Unix(3, 0), ContentProperties: content}
+		b       = &Node{ID: "b", Name: "b.jpg", Kind: "FILE", CreationDate: time.Unix(1, 0), ContentProperties: content}
+		c       = &Node{ID: "c", Name: "c.jpg", Kind: "FILE", CreationDate: time.Unix(2, 0), ContentProperties: ContentProperties{Size: 10, MD5: "AAA"}}
+		d       = &Node{ID: "d", Name: "d.jpg", Kind: "FILE", ContentProperties: ContentProperties{Size: 11, MD5: "aaa"}}
+		e       = &Node{ID: "e", Name: "e.jpg", Kind: "FILE", ContentProperties: ContentProperties{Size: 100, MD5: "bbb"}}
+		f       = &Node{ID: "f", Name: "f.jpg", Kind: "FILE", ContentProperties: ContentProperties{Size: 100, MD5: "bbb"}}
+		deep    = &Node{ID: "deep", Name: "deep", Kind: "FOLDER", Nodes: Nodes{a}}
+		albums  = &Node{ID: "albums", Name: "albums", Kind: "FOLDER", Nodes: Nodes{deep, c, e}}
+		photos  = &Node{ID: "photos", Name: "photos", Kind: "FOLDER", Nodes: Nodes{b, d, f}}
+		root    = &Node{ID: "root", Kind: "FOLDER", Root: true, Nodes: Nodes{albums, photos}}
+		nt      = &Tree{Node: root}
+	)
+	// e is also linked in photos but it is the same node, not a duplicate.
+	photos.Nodes = append(photos.Nodes, e)
+
+	dups, err := nt.FindDuplicates("/")
+	if err != nil {
+		t.Fatalf("nt.FindDuplicates(%q) error: %s", "/", err)
+	}
+	var got [][]string
+	for _, d := range dups {
+		var paths []string
+		for _, m := range d.Matches {
+			paths = append(paths, m.Path)
+		}
+		got = append(got, paths)
+	}
+	want := [][]string{
+		[]string{"/albums/e.jpg", "/photos/f.jpg"},
+		[]string{"/albums/c.jpg", "/albums/deep/a.jpg", "/photos/b.jpg"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("nt.FindDuplicates(%q): want %v got %v", "/", want, got)
+	}
+	if want, got := uint64(100), dups[0].Reclaimable(); want != got {
+		t.Errorf("dups[0].Reclaimable(): want %d got %d", want, got)
+	}
+	if want, got := uint64(20), dups[1].Reclaimable(); want != got {
+		t.Errorf("dups[1].Reclaimable(): want %d got %d", want, got)
+	}
+
+	rules := []struct {
+		keep KeepRule
+		want string
+	}{
+		{KeepOldest, "/photos/b.jpg"},
+		{KeepShortestPath, "/albums/c.jpg"},
+		{KeepInFolder("/ALBUMS/deep/", KeepOldest), "/albums/deep/a.jpg"},
+		{KeepInFolder("/pictures", KeepOldest), "/photos/b.jpg"},
+	}
+	for _, rule := range rules {
+		if got := dups[1].Matches[rule.keep(dups[1].Matches)].Path; got != rule.want {
+			t.Errorf("KeepRule: want %s got %s", rule.want, got)
+		}
+	}
+}
+
+func TestDedupeLink(t *testing.T) {
+	for _, conflict := range []bool{true, false} {
+		var requests []string
+		c := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+path.Clean(r.URL.Path))
+			w.Write([]byte("{}"))
+		})
+		var (
+			content = ContentProperties{Size: 10, MD5: "aaa"}
+			a       = &Node{ID: "a", Name: "a.jpg", Kind: "FILE", Parents: []string{"albums"}, ContentProperties: content, client: c}
+			b       = &Node{ID: "b", Name: "b.jpg", Kind: "FILE", Parents: []string{"photos"}, ContentProperties: content, client: c}
+			other   = &Node{ID: "other", Name: "A.JPG", Kind: "FILE", Parents: []string{"photos"}, client: c}
+			albums  = &Node{ID: "albums", Name: "albums", Kind: "FOLDER", Nodes: Nodes{a}, client: c}
+			photos  = &Node{ID: "photos", Name: "photos", Kind: "FOLDER", Nodes: Nodes{b}, client: c}
+		)
+		if conflict {
+			photos.Nodes = append(photos.Nodes, other)
+		}
+		c.tree = &Tree{Node: &Node{ID: "root", Kind: "FOLDER", Root: true, Nodes: Nodes{albums, photos}}, client: c}
+		c.tree.buildNodeMap(c.tree.Node)
+		d := &Duplicates{Matches: []Match{{Path: "/albums/a.jpg", Node: a}, {Path: "/photos/b.jpg", Node: b}}}
+
+		_, trashed, err := c.tree.Dedupe(d, KeepShortestPath, true)
+		c.Close()
+		if conflict {
+			if err != constants.ErrFileExists || len(trashed) != 0 || len(requests) != 0 {
+				t.Errorf("nt.Dedupe() with a conflict: want %v and no request got %v %v", constants.ErrFileExists, err, requests)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("nt.Dedupe() error: %s", err)
+		}
+		if want := []string{"PUT /trash/b", "PUT /nodes/photos/children/a"}; !reflect.DeepEqual(want, requests) {
+			t.Errorf("nt.Dedupe() requests: want %v got %v", want, requests)
+		}
+		if want := []string{"albums", "photos"}; !reflect.DeepEqual(want, a.Parents) {
+			t.Errorf("nt.Dedupe() parents of the kept copy: want %v got %v", want, a.Parents)
+		}
+	}
+}
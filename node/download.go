@@ -1,14 +1,31 @@
 package node
 
 import (
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"strings"
 
 	"gopkg.in/acd.v0/internal/constants"
 	"gopkg.in/acd.v0/internal/log"
 )
 
+// PartialSuffix is appended to the path of a file being downloaded by
+// DownloadFile, the file is renamed once complete.
+const PartialSuffix = ".partial"
+
+// partialState records the node a partial file is downloaded from, it is
+// saved next to the partial file with a .json extension.
+type partialState struct {
+	ID      string `json:"id"`
+	Version uint64 `json:"version"`
+	Size    uint64 `json:"size"`
+}
+
 // Download downloads the node and returns the body as io.ReadCloser or an
 // error. The caller is responsible for closing the reader.
 func (n *Node) Download() (io.ReadCloser, error) {
@@ -33,3 +50,149 @@ func (n *Node) Download() (io.ReadCloser, error) {
 
 	return res.Body, nil
 }
+
+// DownloadFileOptions are the options of (*Node).DownloadFileWithOptions.
+type DownloadFileOptions struct {
+	// SkipVerify does not verify the MD5 of the content. The size is always
+	// verified.
+	SkipVerify bool
+
+	// Progress, unless nil, is called with the number of bytes of the file
+	// written so far, including the bytes of a resumed partial file.
+	Progress func(written int64)
+}
+
+// DownloadFile downloads the node to the file localPath. The content goes to
+// localPath followed by PartialSuffix first and the file is renamed once
+// complete and verified. If a partial file of the same version and size of
+// the node is found, the download resumes where it stopped.
+func (n *Node) DownloadFile(localPath string) error {
+	return n.DownloadFileWithOptions(localPath, &DownloadFileOptions{})
+}
+
+// DownloadFileWithOptions downloads the node to the file localPath like
+// DownloadFile according to opts. A partial file whose content does not match
+// is removed, so is never mistaken for a complete one nor resumed.
+func (n *Node) DownloadFileWithOptions(localPath string, opts *DownloadFileOptions) error {
+	if n.IsDir() {
+		log.Errorf("%s: cannot download a folder", constants.ErrPathIsFolder)
+		return constants.ErrPathIsFolder
+	}
+
+	partialPath := localPath + PartialSuffix
+	state := partialState{
+		ID:      n.ID,
+		Version: n.ContentProperties.Version,
+		Size:    n.ContentProperties.Size,
+	}
+	f, offset, err := openPartial(partialPath, state)
+	if err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		opts.Progress(offset)
+	}
+
+	var body io.ReadCloser = ioutil.NopCloser(strings.NewReader(""))
+	if offset < n.Size() {
+		log.Debugf("downloading %s to %s from offset %d", n.Name, partialPath, offset)
+		if body, err = n.openRange(offset, -1); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if !opts.SkipVerify {
+		// the content already downloaded is part of the digest.
+		hash := md5.New()
+		if _, err := io.Copy(hash, f); err != nil {
+			body.Close()
+			f.Close()
+			log.Errorf("%s: %s", constants.ErrReadingFileContents, err)
+			return constants.ErrReadingFileContents
+		}
+		body = &VerifyingReader{r: body, hash: hash, read: offset, size: n.Size(), md5: n.ContentProperties.MD5}
+	}
+	var w io.Writer = f
+	if opts.Progress != nil {
+		w = &progressWriter{w: f, written: offset, progress: opts.Progress}
+	}
+	written, err := io.Copy(w, body)
+	body.Close()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	// the VerifyingReader checks the size already when verifying.
+	if err == nil && offset+written != n.Size() {
+		log.Errorf("%s: want %d bytes got %d", constants.ErrSizeMismatch, n.Size(), offset+written)
+		err = constants.ErrSizeMismatch
+	}
+	if err == constants.ErrMD5Mismatch || err == constants.ErrSizeMismatch {
+		os.Remove(partialPath)
+		os.Remove(partialPath + ".json")
+		return err
+	}
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
+		return constants.ErrWritingFileContents
+	}
+
+	if err := os.Rename(partialPath, localPath); err != nil {
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return constants.ErrCreateFile
+	}
+	os.Remove(partialPath + ".json")
+
+	return nil
+}
+
+// openPartial opens the partial file for reading and appending and returns its size if it
+// was downloaded from the same node, or creates it and records state
+// otherwise.
+func openPartial(partialPath string, state partialState) (*os.File, int64, error) {
+	statePath := partialPath + ".json"
+	var saved partialState
+	if content, err := ioutil.ReadFile(statePath); err == nil && json.Unmarshal(content, &saved) == nil && saved == state {
+		if f, err := os.OpenFile(partialPath, os.O_RDWR|os.O_APPEND, 0666); err == nil {
+			if stat, err := f.Stat(); err == nil && uint64(stat.Size()) <= state.Size {
+				log.Debugf("resuming %s at offset %d", partialPath, stat.Size())
+				return f, stat.Size(), nil
+			}
+			f.Close()
+		}
+	}
+
+	f, err := os.Create(partialPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return nil, 0, constants.ErrCreateFile
+	}
+	content, err := json.Marshal(&state)
+	if err != nil {
+		f.Close()
+		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
+		return nil, 0, constants.ErrJSONEncoding
+	}
+	if err := ioutil.WriteFile(statePath, content, 0666); err != nil {
+		f.Close()
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return nil, 0, constants.ErrCreateFile
+	}
+
+	return f, 0, nil
+}
+
+// progressWriter calls progress with the number of bytes written after every
+// write.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	progress func(written int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.written += int64(n)
+	pw.progress(pw.written)
+
+	return n, err
+}
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func TestDownloadFile(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	var ranges []string
+	c := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	})
+	defer c.Close()
+	dir, err := ioutil.TempDir("", "acd-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		partial   string
+		version   uint64
+		wantRange string
+	}{
+		// no partial file
+		{"", 0, "bytes=0-"},
+		// a partial file of the same version resumes
+		{"01234", 2, "bytes=5-"},
+		// a partial file of another version restarts
+		{"01234", 1, "bytes=0-"},
+		// a complete partial file is only renamed
+		{string(content), 2, ""},
+	}
+	for _, test := range tests {
+		ranges = nil
+		localPath := path.Join(dir, "file")
+		n := &Node{ID: "file", Kind: "FILE", ContentProperties: ContentProperties{Version: 2, Size: uint64(len(content))}, client: c}
+		if test.partial != "" {
+			ioutil.WriteFile(localPath+PartialSuffix, []byte(test.partial), 0644)
+			state := fmt.Sprintf(`{"id":"file","version":%d,"size":%d}`, test.version, len(content))
+			ioutil.WriteFile(localPath+PartialSuffix+".json", []byte(state), 0644)
+		}
+
+		if err := n.DownloadFile(localPath); err != nil {
+			t.Fatalf("n.DownloadFile(%q) error: %s", localPath, err)
+		}
+		got, _ := ioutil.ReadFile(localPath)
+		if !bytes.Equal(content, got) {
+			t.Errorf("partial %q version %d: want %q got %q", test.partial, test.version, content, got)
+		}
+		if test.wantRange == "" && len(ranges) != 0 || test.wantRange != "" && (len(ranges) != 1 || ranges[0] != test.wantRange) {
+			t.Errorf("partial %q version %d: want range %q got %v", test.partial, test.version, test.wantRange, ranges)
+		}
+		if _, err := os.Stat(localPath + PartialSuffix); !os.IsNotExist(err) {
+			t.Errorf("partial %q version %d: the partial file was not renamed", test.partial, test.version)
+		}
+		if _, err := os.Stat(localPath + PartialSuffix + ".json"); !os.IsNotExist(err) {
+			t.Errorf("partial %q version %d: the partial state was not removed", test.partial, test.version)
+		}
+		os.Remove(localPath)
+	}
+}
+
+func TestDownloadFileCorrupt(t *testing.T) {
+	c := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader("corrupt!"))
+	})
+	defer c.Close()
+	dir, err := ioutil.TempDir("", "acd-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	localPath := path.Join(dir, "file")
+	n := &Node{ID: "file", Kind: "FILE", ContentProperties: ContentProperties{Size: 8, MD5: "9a0364b9e99bb480dd25e1f0284c8555"}, client: c}
+	if want, got := constants.ErrMD5Mismatch, n.DownloadFile(localPath); want != got {
+		t.Errorf("n.DownloadFile(%q) error: want %v got %v", localPath, want, got)
+	}
+	for _, p := range []string{localPath, localPath + PartialSuffix, localPath + PartialSuffix + ".json"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("n.DownloadFile(%q) of a corrupt node: %s was left behind", localPath, p)
+		}
+	}
+
+	// verification can be skipped.
+	if err := n.DownloadFileWithOptions(localPath, &DownloadFileOptions{SkipVerify: true}); err != nil {
+		t.Errorf("n.DownloadFileWithOptions(%q, SkipVerify) error: %s", localPath, err)
+	}
+
+	// but not the size, a short content does not replace the file.
+	n.ContentProperties.Size = 10
+	if want, got := constants.ErrSizeMismatch, n.DownloadFileWithOptions(localPath, &DownloadFileOptions{SkipVerify: true}); want != got {
+		t.Errorf("n.DownloadFileWithOptions(%q, SkipVerify) of a short content error: want %v got %v", localPath, want, got)
+	}
+	if content, err := ioutil.ReadFile(localPath); err != nil || string(content) != "corrupt!" {
+		t.Errorf("n.DownloadFileWithOptions(%q, SkipVerify) of a short content replaced the file: %q, %v", localPath, content, err)
+	}
+}
@@ -0,0 +1,55 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// DuplicateError is returned when the server rejects an upload with a 409
+// Conflict because a node with the same name exists in the folder or, when
+// the upload lets the server deduplicate, a node with the same content exists
+// anywhere on the drive.
+type DuplicateError struct {
+	// ID is the ID of the existing node.
+	ID string
+
+	// Node is the existing node, nil if it is not in the NodeTree.
+	Node *Node
+
+	// Message is the reason given by the server.
+	Message string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s: node %s already exists", constants.ErrResponseDuplicateExists, e.ID)
+}
+
+// duplicateError returns the DuplicateError of the 409 Conflict response res.
+// The response is closed.
+func (n *Node) duplicateError(res *http.Response) error {
+	defer res.Body.Close()
+	var body struct {
+		Message string `json:"message"`
+		Info    struct {
+			NodeID string `json:"nodeId"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || body.Info.NodeID == "" {
+		log.Errorf("{code: %s} %s: the existing node is unknown", res.Status, constants.ErrResponseDuplicateExists)
+		return constants.ErrResponseDuplicateExists
+	}
+
+	derr := &DuplicateError{ID: body.Info.NodeID, Message: body.Message}
+	if nt := n.client.GetNodeTree(); nt != nil {
+		nt.mu.RLock()
+		derr.Node = nt.nodeMap[derr.ID]
+		nt.mu.RUnlock()
+	}
+	log.Debugf("%s", derr)
+
+	return derr
+}
@@ -9,17 +9,33 @@ import (
 )
 
 // FindNode finds a node for a particular path.
+func (nt *Tree) FindNode(path string) (*Node, error) {
+	node, found := nt.Lookup(path)
+	if !found {
+		log.Errorf("%s: %s", constants.ErrNodeNotFound, strings.TrimPrefix(path, "/"))
+		return nil, constants.ErrNodeNotFound
+	}
+
+	return node, nil
+}
+
+// Lookup returns the node of path like FindNode, without logging an error if
+// there is none. It is safe to call while nodes are added or removed, e.g. by
+// parallel uploads.
 // TODO(kalbasit): This does not perform well, this should be cached in a map
 // path->node and calculated on load (fresh, cache, refresh).
-func (nt *Tree) FindNode(path string) (*Node, error) {
+func (nt *Tree) Lookup(path string) (*Node, bool) {
 	// replace multiple n*/ with /
 	re := regexp.MustCompile("/[/]*")
 	path = string(re.ReplaceAll([]byte(path), []byte("/")))
 	// chop off the first /.
 	path = strings.TrimPrefix(path, "/")
+
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
 	// did we ask for the root node?
 	if path == "" {
-		return nt.Node, nil
+		return nt.Node, true
 	}
 
 	// initialize our search from the root node
@@ -39,17 +55,18 @@ func (nt *Tree) FindNode(path string) (*Node, error) {
 		}
 
 		if !found {
-			log.Errorf("%s: %s", constants.ErrNodeNotFound, path)
-			return nil, constants.ErrNodeNotFound
+			return nil, false
 		}
 	}
 
-	return node, nil
+	return node, true
 }
 
 // FindByID returns the node identified by the ID.
 func (nt *Tree) FindByID(id string) (*Node, error) {
+	nt.mu.RLock()
 	n, found := nt.nodeMap[id]
+	nt.mu.RUnlock()
 	if !found {
 		log.Errorf("%s: ID %q", constants.ErrNodeNotFound, id)
 		return nil, constants.ErrNodeNotFound
@@ -57,3 +74,47 @@ func (nt *Tree) FindByID(id string) (*Node, error) {
 
 	return n, nil
 }
+
+// PathOf returns the full path of the node. A node with several parents is
+// resolved through the first of its parents present in the tree.
+func (nt *Tree) PathOf(n *Node) (string, error) {
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
+	p, found := nt.pathOf(n, func(id string) *Node { return nt.nodeMap[id] })
+	if !found {
+		log.Errorf("%s: no path to ID %q", constants.ErrNodeNotFound, n.ID)
+		return "", constants.ErrNodeNotFound
+	}
+
+	return p, nil
+}
+
+// pathOf returns the full path of the node, its parents being found by
+// lookup, and whether there is one, the parents of a node may form a cycle.
+func (nt *Tree) pathOf(n *Node, lookup func(string) *Node) (string, bool) {
+	var (
+		parts []string
+		seen  = make(map[string]bool)
+	)
+
+	for !n.Root && n != nt.Node {
+		if seen[n.ID] {
+			return "", false
+		}
+		seen[n.ID] = true
+		parts = append([]string{n.Name}, parts...)
+
+		var parent *Node
+		for _, parentID := range n.Parents {
+			if parent = lookup(parentID); parent != nil {
+				break
+			}
+		}
+		if parent == nil {
+			return "", false
+		}
+		n = parent
+	}
+
+	return "/" + strings.Join(parts, "/"), true
+}
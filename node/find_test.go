@@ -44,3 +44,26 @@ func TestFindById(t *testing.T) {
 		}
 	}
 }
+
+func TestPathOf(t *testing.T) {
+	tests := []string{
+		"/",
+		"/README.md",
+		"/pictures",
+		"/pictures/logo.png",
+	}
+
+	for _, test := range tests {
+		n, err := Mocked.FindByID(test)
+		if err != nil {
+			t.Fatalf("MockNodeTree.FindByID(%q) error: %s", test, err)
+		}
+		path, err := Mocked.PathOf(n)
+		if err != nil {
+			t.Errorf("MockNodeTree.PathOf(%q) error: %s", test, err)
+		}
+		if want, got := test, path; want != got {
+			t.Errorf("MockNodeTree.PathOf(%q): want %s got %s", test, want, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package node
+
+import (
+	"path"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// Glob returns the paths of all nodes matching pattern or nil if there is no
+// matching node. The syntax of each path element is the one of path.Match,
+// and an element consisting only of ** matches zero or more folders. Like
+// (*Tree).FindNode, the matching is case-insensitive. The paths are returned
+// in a deterministic order, folders being explored sorted by name.
+func (nt *Tree) Glob(pattern string) ([]string, error) {
+	return nt.GlobFunc(pattern, func(_ string, n *Node) string { return n.Name })
+}
+
+// GlobFunc returns the paths of all nodes matching pattern like Glob, the name
+// of every node being the one returned by name, which is given the path of the
+// folder of the node, e.g. to match decrypted names.
+func (nt *Tree) GlobFunc(pattern string, name func(dir string, n *Node) string) ([]string, error) {
+	var (
+		matches []string
+		seen    = make(map[string]bool)
+	)
+
+	pattern = strings.TrimPrefix(cleanPath(pattern), "/")
+	var parts []string
+	if pattern != "" {
+		parts = strings.Split(strings.ToLower(pattern), "/")
+	}
+	for _, part := range parts {
+		if _, err := path.Match(part, ""); err != nil {
+			log.Errorf("%s: %s", constants.ErrInvalidGlobPattern, pattern)
+			return nil, constants.ErrInvalidGlobPattern
+		}
+	}
+
+	nt.glob("/", nt.Node, parts, name, make(map[string]bool), func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			matches = append(matches, p)
+		}
+	})
+
+	return matches, nil
+}
+
+// HasMeta reports whether pattern contains any of the magic characters
+// recognized by (*Tree).Glob.
+func HasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+func (nt *Tree) glob(dir string, n *Node, parts []string, name func(string, *Node) string, ancestors map[string]bool, match func(string)) {
+	if len(parts) == 0 {
+		match(dir)
+		return
+	}
+	if !n.IsDir() {
+		return
+	}
+
+	part := parts[0]
+	if part == "**" {
+		// ** matches the folder itself, then any of its sub-folders below.
+		nt.glob(dir, n, parts[1:], name, ancestors, match)
+	}
+
+	ancestors[n.ID] = true
+	defer delete(ancestors, n.ID)
+	for _, child := range n.sortedNodes() {
+		if ancestors[child.ID] {
+			continue
+		}
+		childName := name(dir, child)
+		if part == "**" {
+			if child.IsDir() {
+				nt.glob(joinPath(dir, childName), child, parts, name, ancestors, match)
+			}
+			continue
+		}
+		if matched, _ := path.Match(part, strings.ToLower(childName)); matched {
+			nt.glob(joinPath(dir, childName), child, parts[1:], name, ancestors, match)
+		}
+	}
+}
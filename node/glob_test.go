@@ -0,0 +1,41 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	tests := map[string][]string{
+		"/":                  []string{"/"},
+		"/*":                 []string{"/pictures", "/README.md"},
+		"/readme.*":          []string{"/README.md"},
+		"README.M?":          []string{"/README.md"},
+		"/[p-r]*":            []string{"/pictures", "/README.md"},
+		"/[^p]*":             []string{"/README.md"},
+		"/PICTURES/*.png":    []string{"/pictures/logo.png"},
+		"/*/*":               []string{"/pictures/logo.png"},
+		"/**":                []string{"/", "/pictures"},
+		"/**/*.png":          []string{"/pictures/logo.png"},
+		"/**/*":              []string{"/pictures", "/README.md", "/pictures/logo.png"},
+		"/**/pictures/**/*g": []string{"/pictures/logo.png"},
+		"/*.png":             nil,
+		"/README.md/*":       nil,
+	}
+
+	for pattern, want := range tests {
+		got, err := Mocked.Glob(pattern)
+		if err != nil {
+			t.Errorf("Mocked.Glob(%q) error: %s", pattern, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("Mocked.Glob(%q): want %v got %v", pattern, want, got)
+		}
+	}
+}
+
+func TestGlobInvalidPattern(t *testing.T) {
+	if _, err := Mocked.Glob("/[a-"); err == nil {
+		t.Errorf("Mocked.Glob(%q): want an error got nil", "/[a-")
+	}
+}
@@ -0,0 +1,74 @@
+package node
+
+import "sort"
+
+// AddLabels adds the labels to the node on the server and in the cache.
+func (n *Node) AddLabels(labels ...string) error {
+	newLabels := append([]string{}, n.Labels...)
+	for _, label := range labels {
+		if !n.HasLabel(label) {
+			newLabels = append(newLabels, label)
+		}
+	}
+	if len(newLabels) == len(n.Labels) {
+		return nil
+	}
+
+	return n.setLabels(newLabels)
+}
+
+// RemoveLabels removes the labels from the node on the server and in the
+// cache.
+func (n *Node) RemoveLabels(labels ...string) error {
+	newLabels := diffSliceStr(n.Labels, labels)
+	if len(newLabels) == len(n.Labels) {
+		return nil
+	}
+
+	return n.setLabels(newLabels)
+}
+
+// HasLabel returns whether the node is labeled with label.
+func (n *Node) HasLabel(label string) bool {
+	for _, l := range n.Labels {
+		if l == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindByLabel returns all of the nodes of the tree labeled with label, sorted
+// by ID. It does not walk the tree, it looks up every node known by ID
+// instead.
+func (nt *Tree) FindByLabel(label string) Nodes {
+	var nodes Nodes
+	for _, n := range nt.nodeMap {
+		if n.HasLabel(label) {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Sort(byID(nodes))
+
+	return nodes
+}
+
+func (n *Node) setLabels(labels []string) error {
+	if labels == nil {
+		labels = []string{}
+	}
+	if err := n.patch(&patchNode{Labels: &labels}); err != nil {
+		return err
+	}
+
+	// the response omits the labels when there are none left.
+	n.Labels = labels
+	return nil
+}
+
+type byID Nodes
+
+func (ns byID) Len() int           { return len(ns) }
+func (ns byID) Swap(i, j int)      { ns[i], ns[j] = ns[j], ns[i] }
+func (ns byID) Less(i, j int) bool { return ns[i].ID < ns[j].ID }
@@ -0,0 +1,36 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindByLabel(t *testing.T) {
+	var (
+		a    = &Node{ID: "a", Name: "a", Kind: "FILE", Labels: []string{"work", "photos"}}
+		b    = &Node{ID: "b", Name: "b", Kind: "FILE", Labels: []string{"work"}}
+		c    = &Node{ID: "c", Name: "c", Kind: "FILE"}
+		root = &Node{ID: "root", Kind: "FOLDER", Root: true, Nodes: Nodes{c, b, a}}
+		nt   = &Tree{
+			Node: root,
+			nodeMap: map[string]*Node{
+				"root": root,
+				"a":    a,
+				"b":    b,
+				"c":    c,
+			},
+		}
+	)
+
+	tests := map[string]Nodes{
+		"work":   {a, b},
+		"photos": {a},
+		"Work":   nil,
+		"music":  nil,
+	}
+	for label, want := range tests {
+		if got := nt.FindByLabel(label); !reflect.DeepEqual(want, got) {
+			t.Errorf("nt.FindByLabel(%q): want %v got %v", label, want, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+type (
+	moveChild struct {
+		FromParent string `json:"fromParent"`
+		ChildID    string `json:"childId"`
+	}
+
+	patchNode struct {
+		Name   string    `json:"name,omitempty"`
+		Labels *[]string `json:"labels,omitempty"`
+	}
+)
+
+// CheckMove returns the error moving the node to the folder to under name
+// would fail with, without changing anything: an invalid name, a destination
+// which is not a folder, or a folder moved into itself. A name already taken
+// is not checked.
+func (n *Node) CheckMove(to *Node, name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if !to.IsDir() {
+		log.Errorf("%s: %s", constants.ErrPathIsNotFolder, to.Name)
+		return constants.ErrPathIsNotFolder
+	}
+	if nt := n.client.GetNodeTree(); nt != nil && n.IsDir() && nt.isAncestor(n, to) {
+		log.Errorf("%s: %s", constants.ErrCannotMoveIntoItself, n.Name)
+		return constants.ErrCannotMoveIntoItself
+	}
+
+	return nil
+}
+
+// Rename renames the node on the server. The NodeTree is updated as well.
+func (n *Node) Rename(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if name == n.Name {
+		return nil
+	}
+	if nt := n.client.GetNodeTree(); nt != nil {
+		for _, parentID := range n.Parents {
+			if parent, found := nt.nodeMap[parentID]; found && parent.childNamed(name, n) != nil {
+				log.Errorf("%s: %s", constants.ErrFileExists, name)
+				return constants.ErrFileExists
+			}
+		}
+	}
+
+	return n.patch(&patchNode{Name: name})
+}
+
+// Move moves the node from its parent folder from to the folder to on the
+// server. The other parents of the node, if any, are left untouched. The
+// NodeTree is updated as well.
+func (n *Node) Move(from, to *Node) error {
+	if err := n.CheckMove(to, n.Name); err != nil {
+		return err
+	}
+	if from.ID == to.ID {
+		return nil
+	}
+	if to.childNamed(n.Name, n) != nil {
+		log.Errorf("%s: %s", constants.ErrFileExists, n.Name)
+		return constants.ErrFileExists
+	}
+
+	return n.move(from, to)
+}
+
+// patch updates the metadata of the node on the server with p.
+func (n *Node) patch(p *patchNode) error {
+	jsonBytes, err := json.Marshal(p)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
+		return constants.ErrJSONEncoding
+	}
+	patchURL := n.client.GetMetadataURL(fmt.Sprintf("nodes/%s", n.ID))
+	req, err := http.NewRequest("PATCH", patchURL, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return constants.ErrCreatingHTTPRequest
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	var node Node
+	if err := json.NewDecoder(res.Body).Decode(&node); err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONDecodingResponseBody, err)
+		return constants.ErrJSONDecodingResponseBody
+	}
+
+	return n.update(&node)
+}
+
+// move moves the node from the folder from to the folder to on the server and
+// in the tree.
+func (n *Node) move(from, to *Node) error {
+	jsonBytes, err := json.Marshal(&moveChild{FromParent: from.ID, ChildID: n.ID})
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
+		return constants.ErrJSONEncoding
+	}
+	postURL := n.client.GetMetadataURL(fmt.Sprintf("nodes/%s/children", to.ID))
+	req, err := http.NewRequest("POST", postURL, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return constants.ErrCreatingHTTPRequest
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	for i, parentID := range n.Parents {
+		if parentID == from.ID {
+			n.Parents[i] = to.ID
+		}
+	}
+	from.RemoveChild(n)
+	to.AddChild(n)
+
+	return nil
+}
+
+// checkName returns ErrInvalidNodeName if name cannot be the name of a node.
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		log.Errorf("%s: %q", constants.ErrInvalidNodeName, name)
+		return constants.ErrInvalidNodeName
+	}
+
+	return nil
+}
+
+// childNamed returns the child of n named name, case-insensitively, other than
+// except or nil if there is none.
+func (n *Node) childNamed(name string, except *Node) *Node {
+	for _, c := range n.Nodes {
+		if c != except && strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// isAncestor returns true if ancestor is n or one of the folders above n.
+func (nt *Tree) isAncestor(ancestor, n *Node) bool {
+	seen := make(map[string]bool)
+	queue := []*Node{n}
+	for len(queue) > 0 {
+		n, queue = queue[0], queue[1:]
+		if n.ID == ancestor.ID {
+			return true
+		}
+		if seen[n.ID] {
+			continue
+		}
+		seen[n.ID] = true
+		for _, parentID := range n.Parents {
+			if parent, found := nt.nodeMap[parentID]; found {
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	return false
+}
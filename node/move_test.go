@@ -0,0 +1,69 @@
+package node
+
+import (
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func TestIsAncestor(t *testing.T) {
+	tests := []struct {
+		ancestor, n string
+		want        bool
+	}{
+		{"/", "/pictures/logo.png", true},
+		{"/pictures", "/pictures/logo.png", true},
+		{"/pictures", "/pictures", true},
+		{"/pictures", "/README.md", false},
+		{"/pictures/logo.png", "/pictures", false},
+	}
+
+	for _, test := range tests {
+		ancestor, _ := Mocked.FindByID(test.ancestor)
+		n, _ := Mocked.FindByID(test.n)
+		if got := Mocked.isAncestor(ancestor, n); got != test.want {
+			t.Errorf("Mocked.isAncestor(%q, %q): want %t got %t", test.ancestor, test.n, test.want, got)
+		}
+	}
+}
+
+func TestChildNamed(t *testing.T) {
+	readme, _ := Mocked.FindByID("/README.md")
+	if got := Mocked.Node.childNamed("readme.MD", nil); got != readme {
+		t.Errorf("Mocked.Node.childNamed(%q): want %v got %v", "readme.MD", readme, got)
+	}
+	if got := Mocked.Node.childNamed("readme.MD", readme); got != nil {
+		t.Errorf("Mocked.Node.childNamed(%q, readme): want nil got %v", "readme.MD", got)
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	var (
+		c      = &testClient{}
+		root   = &Node{ID: "root", Kind: "FOLDER", client: c}
+		folder = &Node{ID: "folder", Kind: "FOLDER", Parents: []string{"root"}, client: c}
+		sub    = &Node{ID: "sub", Kind: "FOLDER", Parents: []string{"folder"}, client: c}
+		file   = &Node{ID: "file", Kind: "FILE", Parents: []string{"folder"}, client: c}
+	)
+	c.tree = &Tree{Node: root, nodeMap: map[string]*Node{"root": root, "folder": folder, "sub": sub, "file": file}}
+
+	tests := []struct {
+		n, to *Node
+		name  string
+		want  error
+	}{
+		{file, root, "file", nil},
+		{folder, root, "renamed", nil},
+		{file, root, "..", constants.ErrInvalidNodeName},
+		{file, root, "a/b", constants.ErrInvalidNodeName},
+		{folder, file, "folder", constants.ErrPathIsNotFolder},
+		{folder, folder, "folder", constants.ErrCannotMoveIntoItself},
+		{folder, sub, "folder", constants.ErrCannotMoveIntoItself},
+		{sub, root, "sub", nil},
+	}
+	for _, test := range tests {
+		if got := test.n.CheckMove(test.to, test.name); got != test.want {
+			t.Errorf("%s.CheckMove(%s, %q): want %v got %v", test.n.ID, test.to.ID, test.name, test.want, got)
+		}
+	}
+}
@@ -38,21 +38,33 @@ type (
 		CreationDate      time.Time         `json:"creationDate,omitempty"`
 		ModifiedDate      time.Time         `json:"modifiedDate,omitempty"`
 		Version           uint64            `json:"version,omitempty"`
-		TempLink          string            `json:"tempLink,omitempty"`
+		TempLinkURL       string            `json:"tempLink,omitempty"`
 		ContentProperties ContentProperties `json:"contentProperties,omitempty"`
+		Properties        Properties        `json:"properties,omitempty"`
 
 		// Internal
-		Nodes  Nodes `json:"nodes,omitempty"`
-		Root   bool  `json:"root,omitempty"`
-		client client
+		Nodes           Nodes `json:"nodes,omitempty"`
+		Root            bool  `json:"root,omitempty"`
+		client          client
+		tempLinkExpires time.Time
+	}
+
+	// Properties are the application-specific properties of a node, they are
+	// indexed by owner (the ID of the application which set them) then by key.
+	Properties map[string]map[string]string
+
+	// Metadata holds the optional metadata of a node being created.
+	Metadata struct {
+		Labels     []string
+		Properties Properties
 	}
 
 	newNode struct {
-		Name       string            `json:"name,omitempty"`
-		Kind       string            `json:"kind,omitempty"`
-		Labels     []string          `json:"labels,omitempty"`
-		Properties map[string]string `json:"properties"`
-		Parents    []string          `json:"parents"`
+		Name       string     `json:"name,omitempty"`
+		Kind       string     `json:"kind,omitempty"`
+		Labels     []string   `json:"labels,omitempty"`
+		Properties Properties `json:"properties,omitempty"`
+		Parents    []string   `json:"parents"`
 	}
 
 	client interface {
@@ -97,16 +109,30 @@ func (n *Node) Available() bool {
 // AddChild add a new child for the node
 func (n *Node) AddChild(child *Node) {
 	log.Debugf("adding %s under %s", child.Name, n.Name)
+	nt := n.tree()
+	if nt != nil {
+		nt.mu.Lock()
+		defer nt.mu.Unlock()
+	}
 	n.Nodes = append(n.Nodes, child)
 	child.client = n.client
+
+	// make sure the tree can find the child by ID
+	if nt != nil && nt.nodeMap != nil {
+		nt.nodeMap[child.ID] = child
+	}
 }
 
 // RemoveChild remove a new child for the node
 func (n *Node) RemoveChild(child *Node) {
+	if nt := n.tree(); nt != nil {
+		nt.mu.Lock()
+		defer nt.mu.Unlock()
+	}
 	found := false
 
-	for i, n := range n.Nodes {
-		if n == child {
+	for i, c := range n.Nodes {
+		if c == child {
 			if i < len(n.Nodes)-1 {
 				copy(n.Nodes[i:], n.Nodes[i+1:])
 			}
@@ -119,7 +145,22 @@ func (n *Node) RemoveChild(child *Node) {
 	log.Debugf("removing %s from %s: %t", child.Name, n.Name, found)
 }
 
+// tree returns the NodeTree of the client of the node, if any.
+func (n *Node) tree() *Tree {
+	if n.client == nil {
+		return nil
+	}
+
+	return n.client.GetNodeTree()
+}
+
 func (n *Node) update(newNode *Node) error {
+	// decoding into an existing map merges the keys, reset the properties so
+	// the deleted ones are gone.
+	if newNode.Properties != nil {
+		n.Properties = nil
+	}
+
 	// encode the newNode to JSON.
 	v, err := json.Marshal(newNode)
 	if err != nil {
@@ -127,7 +168,11 @@ func (n *Node) update(newNode *Node) error {
 		return constants.ErrJSONEncoding
 	}
 
-	// decode it back to n
+	// decode it back to n, the name of n may be read by another upload.
+	if nt := n.tree(); nt != nil {
+		nt.mu.Lock()
+		defer nt.mu.Unlock()
+	}
 	if err := json.Unmarshal(v, n); err != nil {
 		log.Errorf("error decoding the node from JSON: %s", err)
 		return constants.ErrJSONDecoding
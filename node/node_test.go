@@ -0,0 +1,54 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveChild(t *testing.T) {
+	var (
+		a      = &Node{ID: "a", Name: "a", Kind: "FILE"}
+		b      = &Node{ID: "b", Name: "b", Kind: "FILE"}
+		c      = &Node{ID: "c", Name: "c", Kind: "FILE"}
+		folder = &Node{ID: "folder", Kind: "FOLDER", Nodes: Nodes{a, b, c}}
+	)
+
+	folder.RemoveChild(b)
+	if want, got := (Nodes{a, c}), folder.Nodes; !reflect.DeepEqual(want, got) {
+		t.Errorf("folder.RemoveChild(b): want %v got %v", want, got)
+	}
+	folder.RemoveChild(c)
+	folder.RemoveChild(c)
+	if want, got := (Nodes{a}), folder.Nodes; !reflect.DeepEqual(want, got) {
+		t.Errorf("folder.RemoveChild(c): want %v got %v", want, got)
+	}
+}
+
+func TestUpdateProperties(t *testing.T) {
+	n := &Node{
+		ID: "a",
+		Properties: Properties{
+			"owner": {"deleted": "1", "kept": "1"},
+		},
+	}
+	if err := n.update(&Node{ID: "a", Properties: Properties{"owner": {"kept": "2"}}}); err != nil {
+		t.Fatalf("n.update() error: %s", err)
+	}
+	if want, got := (Properties{"owner": {"kept": "2"}}), n.Properties; !reflect.DeepEqual(want, got) {
+		t.Errorf("n.update().Properties: want %v got %v", want, got)
+	}
+	if value, found := n.Property("owner", "kept"); !found || value != "2" {
+		t.Errorf("n.Property(%q, %q): want 2, true got %s, %t", "owner", "kept", value, found)
+	}
+	if _, found := n.Property("other", "kept"); found {
+		t.Errorf("n.Property(%q, %q): want not found", "other", "kept")
+	}
+
+	// a response without properties must not drop the cached ones.
+	if err := n.update(&Node{ID: "a", Name: "renamed"}); err != nil {
+		t.Fatalf("n.update() error: %s", err)
+	}
+	if want, got := (Properties{"owner": {"kept": "2"}}), n.Properties; !reflect.DeepEqual(want, got) {
+		t.Errorf("n.update().Properties: want %v got %v", want, got)
+	}
+}
@@ -0,0 +1,231 @@
+package node
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// File is an open node. It reads the content of the node with HTTP Range
+// requests so it can be read from any offset. It implements io.ReadSeeker,
+// io.ReaderAt and io.Closer.
+type File struct {
+	node   *Node
+	size   int64
+	offset int64
+
+	// mu guards closed, ReadAt being called concurrently with Close.
+	mu     sync.Mutex
+	closed bool
+
+	// body streams the content from bodyOffset when reading without
+	// read-ahead.
+	body       io.ReadCloser
+	bodyOffset int64
+
+	// buf holds the content from bufOffset when reading with read-ahead.
+	readAhead int
+	buf       []byte
+	bufOffset int64
+}
+
+// Open opens the node for reading. The caller is responsible for closing the
+// file.
+func (n *Node) Open() (*File, error) {
+	return n.OpenWithReadAhead(0)
+}
+
+// OpenWithReadAhead opens the node for reading, every Read fetching at least
+// readAhead bytes and serving the following reads from memory. It is useful
+// when reading in small pieces or seeking back and forth nearby. A readAhead
+// of 0 streams the content instead.
+func (n *Node) OpenWithReadAhead(readAhead int) (*File, error) {
+	if n.IsDir() {
+		log.Errorf("%s: cannot open a folder", constants.ErrPathIsFolder)
+		return nil, constants.ErrPathIsFolder
+	}
+
+	return &File{
+		node:      n,
+		size:      n.Size(),
+		readAhead: readAhead,
+	}, nil
+}
+
+// Read reads up to len(p) bytes from the current offset.
+func (f *File) Read(p []byte) (int, error) {
+	if f.isClosed() {
+		return 0, constants.ErrFileClosed
+	}
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if f.readAhead > 0 {
+		if f.offset < f.bufOffset || f.offset >= f.bufOffset+int64(len(f.buf)) {
+			length := f.readAhead
+			if len(p) > length {
+				length = len(p)
+			}
+			buf := make([]byte, length)
+			n, err := f.ReadAt(buf, f.offset)
+			if n == 0 {
+				return 0, err
+			}
+			f.buf, f.bufOffset = buf[:n], f.offset
+		}
+		n := copy(p, f.buf[f.offset-f.bufOffset:])
+		f.offset += int64(n)
+		return n, nil
+	}
+
+	if f.body == nil || f.bodyOffset != f.offset {
+		if f.body != nil {
+			f.body.Close()
+		}
+		body, err := f.node.openRange(f.offset, -1)
+		if err != nil {
+			f.body = nil
+			return 0, err
+		}
+		f.body, f.bodyOffset = body, f.offset
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	f.bodyOffset += int64(n)
+	if err == io.EOF && f.offset < f.size {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from offset off. It does not use nor change the
+// offset of Read and Seek, and it is safe to call concurrently.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.isClosed() {
+		return 0, constants.ErrFileClosed
+	}
+	if off < 0 {
+		return 0, constants.ErrInvalidSeek
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	var eof error
+	if remaining := f.size - off; int64(len(p)) > remaining {
+		p, eof = p[:remaining], io.EOF
+	}
+	body, err := f.node.openRange(off, off+int64(len(p))-1)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, eof
+}
+
+// Seek sets the offset of the next Read, interpreted according to whence as
+// in io.Seeker.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.isClosed() {
+		return 0, constants.ErrFileClosed
+	}
+	switch whence {
+	case os.SEEK_SET:
+	case os.SEEK_CUR:
+		offset += f.offset
+	case os.SEEK_END:
+		offset += f.size
+	default:
+		return 0, constants.ErrInvalidSeek
+	}
+	if offset < 0 {
+		return 0, constants.ErrInvalidSeek
+	}
+	f.offset = offset
+
+	return offset, nil
+}
+
+// Size returns the size of the file.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	closed := f.closed
+	f.closed = true
+	f.mu.Unlock()
+	if closed {
+		return constants.ErrFileClosed
+	}
+	f.buf = nil
+	if f.body != nil {
+		return f.body.Close()
+	}
+
+	return nil
+}
+
+// isClosed returns whether the file was closed.
+func (f *File) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.closed
+}
+
+// openRange returns the content of the node from the offset start to the
+// offset end included, or to the end of the content if end is negative.
+func (n *Node) openRange(start, end int64) (io.ReadCloser, error) {
+	url := n.client.GetContentURL(fmt.Sprintf("nodes/%s/content", n.ID))
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return nil, constants.ErrCreatingHTTPRequest
+	}
+	if end < 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return nil, constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return nil, err
+	}
+
+	// the whole content is returned when the range is ignored, skip to start.
+	if res.StatusCode != http.StatusPartialContent {
+		if _, err := io.CopyN(ioutil.Discard, res.Body, start); err != nil {
+			res.Body.Close()
+			log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+			return nil, constants.ErrDoingHTTPRequest
+		}
+	}
+
+	return res.Body, nil
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func newContentTestClient(content []byte) *testClient {
+	return newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nodes/file/content" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	})
+}
+
+func TestFileSeekAndRead(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	for _, readAhead := range []int{0, 4, 64} {
+		c := newContentTestClient(content)
+		n := &Node{ID: "file", Kind: "FILE", ContentProperties: ContentProperties{Size: uint64(len(content))}, client: c}
+		f, err := n.OpenWithReadAhead(readAhead)
+		if err != nil {
+			t.Fatalf("n.OpenWithReadAhead(%d) error: %s", readAhead, err)
+		}
+
+		if _, err := f.Seek(-5, os.SEEK_END); err != nil {
+			t.Fatalf("f.Seek(-5, os.SEEK_END) error: %s", err)
+		}
+		got, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatalf("ioutil.ReadAll(f) error: %s", err)
+		}
+		if want := "fghij"; string(got) != want {
+			t.Errorf("readAhead %d: tail: want %q got %q", readAhead, want, got)
+		}
+
+		f.Seek(2, os.SEEK_SET)
+		f.Seek(3, os.SEEK_CUR)
+		p := make([]byte, 3)
+		if _, err := io.ReadFull(f, p); err != nil {
+			t.Fatalf("io.ReadFull(f) error: %s", err)
+		}
+		if want := "567"; string(p) != want {
+			t.Errorf("readAhead %d: read at 5: want %q got %q", readAhead, want, p)
+		}
+
+		p = make([]byte, 8)
+		nr, err := f.ReadAt(p, 16)
+		if want := "ghij"; nr != 4 || err != io.EOF || string(p[:nr]) != want {
+			t.Errorf("readAhead %d: f.ReadAt(p, 16): want %q, EOF got %q, %v", readAhead, want, p[:nr], err)
+		}
+		if _, err := f.Seek(-1, os.SEEK_SET); err == nil {
+			t.Errorf("readAhead %d: f.Seek(-1, os.SEEK_SET): want an error", readAhead)
+		}
+
+		f.Close()
+		c.Close()
+	}
+}
+
+func TestFileReadAhead(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100)
+	c := newContentTestClient(content)
+	defer c.Close()
+	n := &Node{ID: "file", Kind: "FILE", ContentProperties: ContentProperties{Size: uint64(len(content))}, client: c}
+	f, _ := n.OpenWithReadAhead(50)
+	defer f.Close()
+
+	p := make([]byte, 10)
+	for i := 0; i < 10; i++ {
+		if _, err := f.Read(p); err != nil {
+			t.Fatalf("f.Read() error: %s", err)
+		}
+	}
+	if want, got := 2, c.requests; want != got {
+		t.Errorf("requests reading 100 bytes by 10 with a read-ahead of 50: want %d got %d", want, got)
+	}
+}
+
+func TestFileZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("hello.txt")
+	w.Write([]byte("hello, world"))
+	zw.Close()
+
+	c := newContentTestClient(buf.Bytes())
+	defer c.Close()
+	n := &Node{ID: "file", Kind: "FILE", ContentProperties: ContentProperties{Size: uint64(buf.Len())}, client: c}
+	f, _ := n.Open()
+	defer f.Close()
+
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader(f) error: %s", err)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("zr.File[0].Open() error: %s", err)
+	}
+	defer rc.Close()
+	got, _ := ioutil.ReadAll(rc)
+	if want := "hello, world"; string(got) != want {
+		t.Errorf("hello.txt: want %q got %q", want, got)
+	}
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// AddParent adds the folder parent as an extra parent of the node, the node
+// then lives in all of its parents at the same time without being copied. The
+// NodeTree is updated as well.
+func (n *Node) AddParent(parent *Node) error {
+	if !parent.IsDir() {
+		log.Errorf("%s: %s", constants.ErrPathIsNotFolder, parent.Name)
+		return constants.ErrPathIsNotFolder
+	}
+	if n.hasParent(parent) {
+		return nil
+	}
+	if parent.childNamed(n.Name, n) != nil {
+		log.Errorf("%s: %s", constants.ErrFileExists, n.Name)
+		return constants.ErrFileExists
+	}
+	if nt := n.client.GetNodeTree(); nt != nil && n.IsDir() && nt.isAncestor(n, parent) {
+		log.Errorf("%s: %s", constants.ErrCannotMoveIntoItself, n.Name)
+		return constants.ErrCannotMoveIntoItself
+	}
+
+	if err := n.children("PUT", parent); err != nil {
+		return err
+	}
+	n.Parents = append(n.Parents, parent.ID)
+	parent.AddChild(n)
+
+	return nil
+}
+
+// RemoveParent removes the folder parent from the parents of the node. The
+// last parent of a node cannot be removed, use (*Tree).RemoveNode to trash it
+// instead. The NodeTree is updated as well.
+func (n *Node) RemoveParent(parent *Node) error {
+	if !n.hasParent(parent) {
+		log.Errorf("%s: %s is not a parent of %s", constants.ErrNodeNotFound, parent.Name, n.Name)
+		return constants.ErrNodeNotFound
+	}
+	if len(n.Parents) == 1 {
+		log.Errorf("%s: %s", constants.ErrCannotRemoveLastParent, n.Name)
+		return constants.ErrCannotRemoveLastParent
+	}
+
+	if err := n.children("DELETE", parent); err != nil {
+		return err
+	}
+	parents := make([]string, 0, len(n.Parents)-1)
+	for _, parentID := range n.Parents {
+		if parentID != parent.ID {
+			parents = append(parents, parentID)
+		}
+	}
+	n.Parents = parents
+	parent.RemoveChild(n)
+
+	return nil
+}
+
+// children adds (PUT) or removes (DELETE) the node from the children of
+// parent on the server.
+func (n *Node) children(method string, parent *Node) error {
+	childURL := n.client.GetMetadataURL(fmt.Sprintf("nodes/%s/children/%s", parent.ID, n.ID))
+	req, err := http.NewRequest(method, childURL, nil)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return constants.ErrCreatingHTTPRequest
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+func (n *Node) hasParent(parent *Node) bool {
+	for _, parentID := range n.Parents {
+		if parentID == parent.ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+type (
+	propertyValue struct {
+		Value string `json:"value"`
+	}
+
+	propertiesResponse struct {
+		Data map[string]string `json:"data"`
+	}
+)
+
+// Property returns the cached value of the property key set by owner, and
+// whether it is set at all.
+func (n *Node) Property(owner, key string) (string, bool) {
+	value, found := n.Properties[owner][key]
+	return value, found
+}
+
+// FetchProperties fetches the properties set by owner from the server and
+// updates the cached properties of the node.
+func (n *Node) FetchProperties(owner string) (map[string]string, error) {
+	getURL := n.client.GetMetadataURL(fmt.Sprintf("nodes/%s/properties/%s", n.ID, owner))
+	req, err := http.NewRequest("GET", getURL, nil)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return nil, constants.ErrCreatingHTTPRequest
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return nil, constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	var pr propertiesResponse
+	if err := json.NewDecoder(res.Body).Decode(&pr); err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONDecodingResponseBody, err)
+		return nil, constants.ErrJSONDecodingResponseBody
+	}
+	if n.Properties == nil {
+		n.Properties = make(Properties)
+	}
+	n.Properties[owner] = pr.Data
+
+	return pr.Data, nil
+}
+
+// SetProperty sets the property key of owner to value on the server and in
+// the cache.
+func (n *Node) SetProperty(owner, key, value string) error {
+	jsonBytes, err := json.Marshal(&propertyValue{Value: value})
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
+		return constants.ErrJSONEncoding
+	}
+	if err := n.property("PUT", owner, key, jsonBytes); err != nil {
+		return err
+	}
+
+	if n.Properties == nil {
+		n.Properties = make(Properties)
+	}
+	if n.Properties[owner] == nil {
+		n.Properties[owner] = make(map[string]string)
+	}
+	n.Properties[owner][key] = value
+
+	return nil
+}
+
+// DeleteProperty deletes the property key of owner on the server and in the
+// cache.
+func (n *Node) DeleteProperty(owner, key string) error {
+	if err := n.property("DELETE", owner, key, nil); err != nil {
+		return err
+	}
+	delete(n.Properties[owner], key)
+
+	return nil
+}
+
+func (n *Node) property(method, owner, key string, body []byte) error {
+	propertyURL := n.client.GetMetadataURL(fmt.Sprintf("nodes/%s/properties/%s/%s", n.ID, owner, key))
+	req, err := http.NewRequest(method, propertyURL, bytes.NewBuffer(body))
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return constants.ErrCreatingHTTPRequest
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package node
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+type (
+	// Predicate reports whether the node found at path matches a query.
+	// Predicates are combined with And, Or and Not and evaluated by
+	// (*Tree).Find.
+	Predicate func(path string, n *Node) bool
+
+	// Match is a node matched by (*Tree).Find along with its full path.
+	Match struct {
+		Path string `json:"path"`
+		Node *Node  `json:"node"`
+	}
+)
+
+// Find walks the tree rooted at root and returns every node matching p in the
+// order (*Tree).Walk visits them. A nil p matches every node.
+func (nt *Tree) Find(root string, p Predicate) ([]Match, error) {
+	var matches []Match
+	err := nt.Walk(root, func(path string, n *Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == nil || p(path, n) {
+			matches = append(matches, Match{Path: path, Node: n})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// And returns a Predicate matching when all of ps match.
+func And(ps ...Predicate) Predicate {
+	return func(path string, n *Node) bool {
+		for _, p := range ps {
+			if !p(path, n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a Predicate matching when any of ps matches.
+func Or(ps ...Predicate) Predicate {
+	return func(path string, n *Node) bool {
+		for _, p := range ps {
+			if p(path, n) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a Predicate matching when p does not.
+func Not(p Predicate) Predicate {
+	return func(path string, n *Node) bool {
+		return !p(path, n)
+	}
+}
+
+// NameMatches returns a Predicate matching the nodes whose name matches the
+// shell pattern, as understood by path.Match, case-insensitively.
+func NameMatches(pattern string) Predicate {
+	pattern = strings.ToLower(pattern)
+	return func(_ string, n *Node) bool {
+		matched, _ := path.Match(pattern, strings.ToLower(n.Name))
+		return matched
+	}
+}
+
+// PathMatches returns a Predicate matching the nodes whose full path matches
+// the pattern case-insensitively. The pattern follows the syntax of
+// (*Tree).Glob, including ** to match any number of folders.
+func PathMatches(pattern string) Predicate {
+	parts := splitPath(strings.ToLower(pattern))
+	return func(p string, _ *Node) bool {
+		return matchParts(parts, splitPath(strings.ToLower(p)))
+	}
+}
+
+// KindIs returns a Predicate matching the nodes of the given kind: FILE,
+// FOLDER or ASSET.
+func KindIs(kind string) Predicate {
+	return func(_ string, n *Node) bool {
+		return strings.EqualFold(n.Kind, kind)
+	}
+}
+
+// StatusIs returns a Predicate matching the nodes with the given status, for
+// example AVAILABLE. A Tree only holds the AVAILABLE nodes.
+func StatusIs(status string) Predicate {
+	return func(_ string, n *Node) bool {
+		return strings.EqualFold(n.Status, status)
+	}
+}
+
+// SizeAbove returns a Predicate matching the nodes larger than size bytes.
+func SizeAbove(size uint64) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.ContentProperties.Size > size
+	}
+}
+
+// SizeBelow returns a Predicate matching the nodes smaller than size bytes.
+func SizeBelow(size uint64) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.ContentProperties.Size < size
+	}
+}
+
+// SizeEquals returns a Predicate matching the nodes of exactly size bytes.
+func SizeEquals(size uint64) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.ContentProperties.Size == size
+	}
+}
+
+// ModifiedBefore returns a Predicate matching the nodes modified before t.
+func ModifiedBefore(t time.Time) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.ModifiedDate.Before(t)
+	}
+}
+
+// ModifiedAfter returns a Predicate matching the nodes modified after t.
+func ModifiedAfter(t time.Time) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.ModifiedDate.After(t)
+	}
+}
+
+// ContentDateBefore returns a Predicate matching the nodes whose content date
+// is before t. Nodes without content never match.
+func ContentDateBefore(t time.Time) Predicate {
+	return func(_ string, n *Node) bool {
+		cd := n.ContentProperties.ContentDate
+		return !cd.IsZero() && cd.Before(t)
+	}
+}
+
+// ContentDateAfter returns a Predicate matching the nodes whose content date
+// is after t.
+func ContentDateAfter(t time.Time) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.ContentProperties.ContentDate.After(t)
+	}
+}
+
+// ContentTypeMatches returns a Predicate matching the nodes whose content type
+// matches the shell pattern, for example image/*.
+func ContentTypeMatches(pattern string) Predicate {
+	pattern = strings.ToLower(pattern)
+	return func(_ string, n *Node) bool {
+		matched, _ := path.Match(pattern, strings.ToLower(n.ContentProperties.ContentType))
+		return matched
+	}
+}
+
+// ExtensionIs returns a Predicate matching the nodes with the given extension.
+// A leading dot in ext is ignored.
+func ExtensionIs(ext string) Predicate {
+	ext = strings.TrimPrefix(ext, ".")
+	return func(_ string, n *Node) bool {
+		return strings.EqualFold(n.ContentProperties.Extension, ext)
+	}
+}
+
+// MD5Is returns a Predicate matching the nodes whose content has the given MD5
+// hex digest.
+func MD5Is(md5 string) Predicate {
+	return func(_ string, n *Node) bool {
+		return strings.EqualFold(n.ContentProperties.MD5, md5)
+	}
+}
+
+// LabeledWith returns a Predicate matching the nodes labeled with label.
+func LabeledWith(label string) Predicate {
+	return func(_ string, n *Node) bool {
+		return n.HasLabel(label)
+	}
+}
+
+// splitPath returns the elements of the cleaned path p.
+func splitPath(p string) []string {
+	p = strings.TrimPrefix(cleanPath(p), "/")
+	if p == "" {
+		return nil
+	}
+
+	return strings.Split(p, "/")
+}
+
+// matchParts reports whether the path elements parts match the pattern
+// elements patterns, an element ** matching zero or more path elements.
+func matchParts(patterns, parts []string) bool {
+	if len(patterns) == 0 {
+		return len(parts) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(patterns[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(patterns[0], parts[0]); !matched {
+		return false
+	}
+
+	return matchParts(patterns[1:], parts[1:])
+}
@@ -0,0 +1,250 @@
+package node
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+type queryParser struct {
+	args []string
+	pos  int
+	now  time.Time
+}
+
+// ParseQuery parses a find(1)-like expression into a Predicate. An empty
+// expression matches every node. The supported tests are:
+//
+//	-name PATTERN         the name matches PATTERN
+//	-path PATTERN         the full path matches PATTERN, ** matches folders
+//	-type f|d|a           the node is a file, a folder or an asset
+//	-size [+-]N[ckMGTPE]  the size is greater, lower or equal to N, see ParseSize
+//	-mtime [+-]N          the node was modified more, less or exactly N days ago
+//	-modified-before DATE the node was modified before DATE
+//	-modified-after DATE  the node was modified after DATE
+//	-content-before DATE  the content date is before DATE
+//	-content-after DATE   the content date is after DATE
+//	-content-type PATTERN the content type matches PATTERN, e.g. image/*
+//	-extension EXT        the extension is EXT
+//	-md5 HASH             the MD5 of the content is HASH
+//	-label LABEL          the node has the label LABEL
+//	-status AVAILABLE     the node is available, the tree holds no other
+//	                      status so any other is an error
+//
+// DATE is either 2006-01-02 or RFC 3339. The tests are combined with the
+// operators ( EXPR ), ! EXPR (or -not), EXPR -a EXPR (or -and, or just
+// juxtaposed) and EXPR -o EXPR (or -or), by decreasing precedence.
+func ParseQuery(args []string) (Predicate, error) {
+	if len(args) == 0 {
+		return func(string, *Node) bool { return true }, nil
+	}
+
+	p := &queryParser{args: args, now: time.Now()}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.args) {
+		return nil, p.errorf("unexpected %q", p.args[p.pos])
+	}
+
+	return pred, nil
+}
+
+func (p *queryParser) parseOr() (Predicate, error) {
+	pred, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		pred = Or(pred, right)
+	}
+
+	return pred, nil
+}
+
+func (p *queryParser) parseAnd() (Predicate, error) {
+	pred, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", "-o", "-or", ")":
+			return pred, nil
+		case "-a", "-and":
+			p.pos++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		pred = And(pred, right)
+	}
+}
+
+func (p *queryParser) parseNot() (Predicate, error) {
+	if p.peek() == "!" || p.peek() == "-not" {
+		p.pos++
+		pred, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not(pred), nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Predicate, error) {
+	if p.pos >= len(p.args) {
+		return nil, p.errorf("expected an expression")
+	}
+	test := p.args[p.pos]
+	p.pos++
+	if test == "(" {
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return pred, nil
+	}
+
+	if p.pos >= len(p.args) {
+		return nil, p.errorf("missing argument to %s", test)
+	}
+	arg := p.args[p.pos]
+	p.pos++
+	switch test {
+	case "-name":
+		return NameMatches(arg), nil
+	case "-path":
+		return PathMatches(arg), nil
+	case "-type":
+		kinds := map[string]string{"f": "FILE", "d": "FOLDER", "a": "ASSET"}
+		kind, found := kinds[arg]
+		if !found {
+			return nil, p.errorf("unknown type %q", arg)
+		}
+		return KindIs(kind), nil
+	case "-size":
+		return p.parseSize(arg)
+	case "-mtime":
+		return p.parseMtime(arg)
+	case "-modified-before", "-modified-after", "-content-before", "-content-after":
+		return p.parseDateTest(test, arg)
+	case "-content-type":
+		return ContentTypeMatches(arg), nil
+	case "-extension":
+		return ExtensionIs(arg), nil
+	case "-md5":
+		return MD5Is(arg), nil
+	case "-label":
+		return LabeledWith(arg), nil
+	case "-status":
+		// the trashed and the purged nodes are not in the tree.
+		if !strings.EqualFold(arg, "AVAILABLE") {
+			return nil, p.errorf("status %q never matches, only AVAILABLE nodes are in the tree", arg)
+		}
+		return StatusIs(arg), nil
+	}
+
+	return nil, p.errorf("unknown test %q", test)
+}
+
+func (p *queryParser) parseSize(arg string) (Predicate, error) {
+	sign, arg := splitSign(arg)
+	size, err := ParseSize(arg)
+	if err != nil {
+		return nil, p.errorf("invalid size %q", arg)
+	}
+	switch sign {
+	case '+':
+		return SizeAbove(uint64(size)), nil
+	case '-':
+		return SizeBelow(uint64(size)), nil
+	}
+
+	return SizeEquals(uint64(size)), nil
+}
+
+func (p *queryParser) parseMtime(arg string) (Predicate, error) {
+	sign, arg := splitSign(arg)
+	days, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil {
+		return nil, p.errorf("invalid number of days %q", arg)
+	}
+	day := 24 * time.Hour
+	t := p.now.Add(-time.Duration(days) * day)
+	switch sign {
+	case '+':
+		return ModifiedBefore(t), nil
+	case '-':
+		return ModifiedAfter(t), nil
+	}
+
+	return And(ModifiedBefore(t), ModifiedAfter(t.Add(-day))), nil
+}
+
+func (p *queryParser) parseDateTest(test, arg string) (Predicate, error) {
+	var (
+		t     time.Time
+		err   error
+		valid bool
+	)
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err = time.ParseInLocation(layout, arg, time.Local); err == nil {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, p.errorf("invalid date %q", arg)
+	}
+
+	switch test {
+	case "-modified-before":
+		return ModifiedBefore(t), nil
+	case "-modified-after":
+		return ModifiedAfter(t), nil
+	case "-content-before":
+		return ContentDateBefore(t), nil
+	}
+
+	return ContentDateAfter(t), nil
+}
+
+func (p *queryParser) peek() string {
+	if p.pos >= len(p.args) {
+		return ""
+	}
+
+	return p.args[p.pos]
+}
+
+func (p *queryParser) errorf(format string, v ...interface{}) error {
+	log.Errorf("%s: %s", constants.ErrInvalidQuery, fmt.Sprintf(format, v...))
+	return constants.ErrInvalidQuery
+}
+
+// splitSign returns the leading + or - of s, if any, and the rest of s.
+func splitSign(s string) (byte, string) {
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		return s[0], s[1:]
+	}
+
+	return 0, s
+}
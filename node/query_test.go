@@ -0,0 +1,74 @@
+package node
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := map[string][]string{
+		"":                                      []string{"/", "/pictures", "/pictures/logo.png", "/README.md"},
+		"-type f":                               []string{"/pictures/logo.png", "/README.md"},
+		"-type d":                               []string{"/", "/pictures"},
+		"-name *.PNG":                           []string{"/pictures/logo.png"},
+		"-path /pictures/*":                     []string{"/pictures/logo.png"},
+		"-path /**/*.md":                        []string{"/README.md"},
+		"-size +1k":                             []string{"/pictures/logo.png"},
+		"-size -1k -type f":                     []string{"/README.md"},
+		"-size 740c":                            []string{"/README.md"},
+		"-content-type image/*":                 []string{"/pictures/logo.png"},
+		"-extension .md":                        []string{"/README.md"},
+		"-md5 11C8FAC0D43831697251FD0B869E77D7": []string{"/README.md"},
+		"-mtime -1 -type f":                     []string{"/pictures/logo.png", "/README.md"},
+		"-mtime +1":                             nil,
+		"-modified-before 2015-01-01":           nil,
+		"-content-after 2015-01-01T00:00:00Z -name logo.png": []string{"/pictures/logo.png"},
+		"-name *.md -o -name *.png":                          []string{"/pictures/logo.png", "/README.md"},
+		"! -type d -a ! -name *.md":                          []string{"/pictures/logo.png"},
+		"-type f -a ( -extension md -or -label x )":          []string{"/README.md"},
+		"-not ( -name *.md -o -type d )":                     []string{"/pictures/logo.png"},
+		"-status available -label x":                         nil,
+	}
+
+	for expr, want := range tests {
+		p, err := ParseQuery(strings.Fields(expr))
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error: %s", expr, err)
+			continue
+		}
+		matches, err := Mocked.Find("/", p)
+		if err != nil {
+			t.Errorf("Mocked.Find(%q) error: %s", expr, err)
+		}
+		var got []string
+		for _, m := range matches {
+			got = append(got, m.Path)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("Mocked.Find(%q): want %v got %v", expr, want, got)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []string{
+		"-name",
+		"-type x",
+		"-size 1X",
+		"-size 1e3",
+		"-mtime abc",
+		"-modified-before yesterday",
+		"( -name x",
+		"-name x )",
+		"-unknown x",
+		"-status TRASH",
+		"-name x -o",
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseQuery(strings.Fields(expr)); err == nil {
+			t.Errorf("ParseQuery(%q): want an error got nil", expr)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package node
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// sizeUnits are the binary units of the sizes parsed by ParseSize, c being
+// the bytes of find(1).
+const sizeUnits = "CKMGTPE"
+
+// ParseSize parses a size in bytes, optionally followed by a binary unit such
+// as 512K, 1.5G, 2GiB or 740c, case-insensitive.
+func ParseSize(s string) (int64, error) {
+	var (
+		number = strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B"), "I")
+		unit   = float64(1)
+	)
+	if number != "" {
+		if i := strings.IndexByte(sizeUnits, number[len(number)-1]); i >= 0 {
+			for ; i > 0; i-- {
+				unit *= 1024
+			}
+			number = number[:len(number)-1]
+		}
+	}
+	// only digits and a decimal point, ParseFloat accepts exponents and Inf.
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || strings.Trim(number, "0123456789.") != "" {
+		log.Errorf("%s: %q", constants.ErrInvalidSize, s)
+		return 0, constants.ErrInvalidSize
+	}
+
+	return int64(n * unit), nil
+}
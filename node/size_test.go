@@ -0,0 +1,38 @@
+package node
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":      0,
+		"740":    740,
+		"740c":   740,
+		"100B":   100,
+		"512k":   512 << 10,
+		"512K":   512 << 10,
+		"1.5G":   3 << 29,
+		"2GiB":   2 << 30,
+		"1MB":    1 << 20,
+		" 1T ":   1 << 40,
+		"1e3":    -1,
+		"Inf":    -1,
+		"-1":     -1,
+		"1X":     -1,
+		"":       -1,
+		"G":      -1,
+		"1.5.0G": -1,
+	}
+
+	for s, want := range tests {
+		got, err := ParseSize(s)
+		if want < 0 {
+			if err == nil {
+				t.Errorf("ParseSize(%q): want an error got %d", s, got)
+			}
+			continue
+		}
+		if err != nil || want != got {
+			t.Errorf("ParseSize(%q): want %d got %d %v", s, want, got, err)
+		}
+	}
+}
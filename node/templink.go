@@ -0,0 +1,71 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+var (
+	// TempLinkValidity is how long a temporary link is assumed to remain
+	// valid, the server does not return the expiry along with the link.
+	TempLinkValidity = time.Hour
+
+	// TempLinkRenewBefore is how long before its expiry a cached temporary
+	// link is replaced by a fresh one.
+	TempLinkRenewBefore = 5 * time.Minute
+)
+
+// TempLink is a temporary link to download the content of a node without
+// authentication.
+type TempLink struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// TempLink returns a temporary link to download the content of the node. The
+// link is cached and a fresh one is requested shortly before it expires.
+func (n *Node) TempLink() (*TempLink, error) {
+	if n.IsDir() {
+		log.Errorf("%s: cannot link a folder", constants.ErrPathIsFolder)
+		return nil, constants.ErrPathIsFolder
+	}
+	if n.TempLinkURL != "" && time.Now().Add(TempLinkRenewBefore).Before(n.tempLinkExpires) {
+		return &TempLink{URL: n.TempLinkURL, Expires: n.tempLinkExpires}, nil
+	}
+
+	getURL := n.client.GetMetadataURL(fmt.Sprintf("nodes/%s?tempLink=true", n.ID))
+	req, err := http.NewRequest("GET", getURL, nil)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return nil, constants.ErrCreatingHTTPRequest
+	}
+	requested := time.Now()
+	res, err := n.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return nil, constants.ErrDoingHTTPRequest
+	}
+	if err := n.client.CheckResponse(res); err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	var linked Node
+	if err := json.NewDecoder(res.Body).Decode(&linked); err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONDecodingResponseBody, err)
+		return nil, constants.ErrJSONDecodingResponseBody
+	}
+	if linked.TempLinkURL == "" {
+		log.Errorf("%s: %s", constants.ErrNoTempLink, n.Name)
+		return nil, constants.ErrNoTempLink
+	}
+	n.TempLinkURL = linked.TempLinkURL
+	n.tempLinkExpires = requested.Add(TempLinkValidity)
+
+	return &TempLink{URL: n.TempLinkURL, Expires: n.tempLinkExpires}, nil
+}
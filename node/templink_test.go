@@ -0,0 +1,46 @@
+package node
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTempLink(t *testing.T) {
+	c := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nodes/file" || r.URL.Query().Get("tempLink") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"id":"file","kind":"FILE","tempLink":"https://example.com/link"}`)
+	})
+	defer c.Close()
+	n := &Node{ID: "file", Name: "file", Kind: "FILE", client: c}
+
+	link, err := n.TempLink()
+	if err != nil {
+		t.Fatalf("n.TempLink() error: %s", err)
+	}
+	if want, got := "https://example.com/link", link.URL; want != got {
+		t.Errorf("n.TempLink().URL: want %s got %s", want, got)
+	}
+	if link.Expires.Before(time.Now().Add(TempLinkValidity - time.Minute)) {
+		t.Errorf("n.TempLink().Expires: want about %s from now got %s", TempLinkValidity, link.Expires)
+	}
+
+	// the link is cached until shortly before its expiry.
+	if _, err := n.TempLink(); err != nil {
+		t.Fatalf("n.TempLink() error: %s", err)
+	}
+	if want, got := 1, c.requests; want != got {
+		t.Errorf("requests after a cached n.TempLink(): want %d got %d", want, got)
+	}
+	n.tempLinkExpires = time.Now().Add(TempLinkRenewBefore / 2)
+	if _, err := n.TempLink(); err != nil {
+		t.Fatalf("n.TempLink() error: %s", err)
+	}
+	if want, got := 2, c.requests; want != got {
+		t.Errorf("requests after an expiring n.TempLink(): want %d got %d", want, got)
+	}
+}
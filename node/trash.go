@@ -0,0 +1,146 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+type (
+	// TrashedNode is a node in the trash.
+	TrashedNode struct {
+		*Node
+
+		// OriginalPath is the path the node was trashed from. It is empty if
+		// none of its parents could be found.
+		OriginalPath string
+
+		// TrashedDate is the date the node was trashed, which is the last time
+		// it was modified.
+		TrashedDate time.Time
+	}
+
+	byTrashedDate []*TrashedNode
+)
+
+// ListTrash returns the nodes in the trash, the oldest first.
+func (nt *Tree) ListTrash() ([]*TrashedNode, error) {
+	nodes, err := nt.listNodes("trash")
+	if err != nil {
+		return nil, err
+	}
+
+	trashed := make(map[string]*Node, len(nodes))
+	for _, n := range nodes {
+		nt.setClient(n)
+		trashed[n.ID] = n
+	}
+	lookup := func(id string) *Node {
+		if n, found := nt.nodeMap[id]; found && n.Available() {
+			return n
+		}
+		return trashed[id]
+	}
+
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
+	tns := make([]*TrashedNode, 0, len(nodes))
+	for _, n := range nodes {
+		p, _ := nt.pathOf(n, lookup)
+		tns = append(tns, &TrashedNode{
+			Node:         n,
+			OriginalPath: p,
+			TrashedDate:  n.ModifiedDate,
+		})
+	}
+	sort.Stable(byTrashedDate(tns))
+
+	return tns, nil
+}
+
+// Restore restores the trashed node n to its original parents and adds it back
+// to the NodeTree. If folder is not nil, the node is moved into folder once
+// restored.
+func (nt *Tree) Restore(n *Node, folder *Node) error {
+	postURL := nt.client.GetMetadataURL(fmt.Sprintf("trash/%s/restore", n.ID))
+	req, err := http.NewRequest("POST", postURL, nil)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
+		return constants.ErrCreatingHTTPRequest
+	}
+	res, err := nt.client.Do(req)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
+		return constants.ErrDoingHTTPRequest
+	}
+	if err := nt.client.CheckResponse(res); err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	var restored Node
+	if err := json.NewDecoder(res.Body).Decode(&restored); err != nil {
+		log.Errorf("%s: %s", constants.ErrJSONDecodingResponseBody, err)
+		return constants.ErrJSONDecodingResponseBody
+	}
+
+	// keep the node known by the tree, if any, so the pointers held by the
+	// caller remain valid.
+	if existing, found := nt.nodeMap[n.ID]; found {
+		n = existing
+	}
+	if err := n.update(&restored); err != nil {
+		return err
+	}
+	nt.setClient(n)
+	nt.nodeMap[n.ID] = n
+	nt.attach(n)
+
+	if folder == nil || len(n.Parents) == 0 {
+		return nil
+	}
+	from, found := nt.nodeMap[n.Parents[0]]
+	if !found {
+		from = &Node{ID: n.Parents[0]}
+	}
+
+	return n.move(from, folder)
+}
+
+// attach adds n to its parents present in the tree, and adds to n the nodes of
+// the tree having n as a parent.
+func (nt *Tree) attach(n *Node) {
+	for _, parentID := range n.Parents {
+		if parent, found := nt.nodeMap[parentID]; found && !parent.hasChild(n) {
+			parent.AddChild(n)
+		}
+	}
+	for _, child := range nt.nodeMap {
+		for _, parentID := range child.Parents {
+			if parentID == n.ID && !n.hasChild(child) {
+				n.AddChild(child)
+			}
+		}
+	}
+}
+
+func (n *Node) hasChild(child *Node) bool {
+	for _, c := range n.Nodes {
+		if c == child {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (tns byTrashedDate) Len() int      { return len(tns) }
+func (tns byTrashedDate) Swap(i, j int) { tns[i], tns[j] = tns[j], tns[i] }
+func (tns byTrashedDate) Less(i, j int) bool {
+	return tns[i].TrashedDate.Before(tns[j].TrashedDate)
+}
@@ -0,0 +1,35 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttach(t *testing.T) {
+	var (
+		child  = &Node{ID: "child", Name: "child", Kind: "FILE", Parents: []string{"folder"}}
+		folder = &Node{ID: "folder", Name: "folder", Kind: "FOLDER", Parents: []string{"root"}}
+		root   = &Node{ID: "root", Kind: "FOLDER", Root: true}
+		nt     = &Tree{
+			Node: root,
+			nodeMap: map[string]*Node{
+				"root":   root,
+				"child":  child,
+				"folder": folder,
+			},
+		}
+	)
+
+	// attaching twice must not add the children twice.
+	nt.attach(folder)
+	nt.attach(folder)
+	if want, got := (Nodes{folder}), root.Nodes; !reflect.DeepEqual(want, got) {
+		t.Errorf("nt.attach(folder) root.Nodes: want %v got %v", want, got)
+	}
+	if want, got := (Nodes{child}), folder.Nodes; !reflect.DeepEqual(want, got) {
+		t.Errorf("nt.attach(folder) folder.Nodes: want %v got %v", want, got)
+	}
+	if p, err := nt.PathOf(child); err != nil || p != "/folder/child" {
+		t.Errorf("nt.PathOf(child): want /folder/child got %q, %v", p, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/acd.v0/internal/constants"
@@ -23,6 +24,10 @@ type (
 		client    client
 		cacheFile string
 		nodeMap   map[string]*Node
+
+		// mu guards the nodes of the tree and nodeMap, which the parallel
+		// uploads look up and change.
+		mu sync.RWMutex
 	}
 
 	nodeList struct {
@@ -77,23 +82,10 @@ func (nt *Tree) Close() error {
 // already a directory, MkdirAll does nothing and returns the directory node
 // and nil.
 func (nt *Tree) MkdirAll(path string) (*Node, error) {
-	var (
-		err        error
-		folderNode = nt.Node
-		logLevel   = log.GetLevel()
-		nextNode   *Node
-		node       *Node
-	)
-
 	// Short-circuit if the node already exists!
-	{
-		log.SetLevel(log.DisableLogLevel)
-		node, err = nt.FindNode(path)
-		log.SetLevel(logLevel)
-	}
-	if err == nil {
+	if node, found := nt.Lookup(path); found {
 		if node.IsDir() {
-			return node, err
+			return node, nil
 		}
 		log.Errorf("%s: %s", constants.ErrFileExistsAndIsNotFolder, path)
 		return nil, constants.ErrFileExistsAndIsNotFolder
@@ -109,16 +101,11 @@ func (nt *Tree) MkdirAll(path string) (*Node, error) {
 		return nil, constants.ErrCannotCreateRootNode
 	}
 
+	folderNode := nt.Node
 	for i, part := range parts {
-		{
-			log.SetLevel(log.DisableLogLevel)
-			nextNode, err = nt.FindNode(strings.Join(parts[:i+1], "/"))
-			log.SetLevel(logLevel)
-		}
-		if err != nil && err != constants.ErrNodeNotFound {
-			return nil, err
-		}
-		if err == constants.ErrNodeNotFound {
+		nextNode, found := nt.Lookup(strings.Join(parts[:i+1], "/"))
+		if !found {
+			var err error
 			nextNode, err = folderNode.CreateFolder(part)
 			if err != nil {
 				return nil, err
@@ -179,17 +166,51 @@ func (nt *Tree) loadOrFetch() error {
 
 func (nt *Tree) fetchFresh() error {
 	// grab the list of all of the nodes from the server.
+	nodes, err := nt.listNodes("nodes")
+	if err != nil {
+		return err
+	}
+
+	nodeMap := make(map[string]*Node, len(nodes))
+	for _, node := range nodes {
+		if !node.Available() {
+			continue
+		}
+		nt.setClient(node)
+		nodeMap[node.ID] = node
+	}
+
+	for _, node := range nodeMap {
+		if node.Name == "" && node.IsDir() && len(node.Parents) == 0 {
+			nt.Node = node
+			node.Root = true
+		}
+
+		for _, parentID := range node.Parents {
+			if pn, found := nodeMap[parentID]; found {
+				pn.Nodes = append(pn.Nodes, node)
+			}
+		}
+	}
+
+	nt.nodeMap = nodeMap
+	return nil
+}
+
+// listNodes returns all of the nodes listed by the endpoint, following the
+// pagination.
+func (nt *Tree) listNodes(endpoint string) ([]*Node, error) {
 	var nextToken string
 	var nodes []*Node
 	for {
 		nl := nodeList{
 			Nodes: make([]*Node, 0, 200),
 		}
-		urlStr := nt.client.GetMetadataURL("nodes")
+		urlStr := nt.client.GetMetadataURL(endpoint)
 		u, err := url.Parse(urlStr)
 		if err != nil {
 			log.Errorf("%s: %s", constants.ErrParsingURL, urlStr)
-			return constants.ErrParsingURL
+			return nil, constants.ErrParsingURL
 		}
 
 		v := url.Values{}
@@ -202,19 +223,23 @@ func (nt *Tree) fetchFresh() error {
 		req, err := http.NewRequest("GET", u.String(), nil)
 		if err != nil {
 			log.Errorf("%s: %s", constants.ErrCreatingHTTPRequest, err)
-			return constants.ErrCreatingHTTPRequest
+			return nil, constants.ErrCreatingHTTPRequest
 		}
 		req.Header.Set("Content-Type", "application/json")
 		res, err := nt.client.Do(req)
 		if err != nil {
 			log.Errorf("%s: %s", constants.ErrDoingHTTPRequest, err)
-			return constants.ErrDoingHTTPRequest
+			return nil, constants.ErrDoingHTTPRequest
+		}
+		if err := nt.client.CheckResponse(res); err != nil {
+			return nil, err
 		}
 
-		defer res.Body.Close()
-		if err := json.NewDecoder(res.Body).Decode(&nl); err != nil {
+		err = json.NewDecoder(res.Body).Decode(&nl)
+		res.Body.Close()
+		if err != nil {
 			log.Errorf("%s: %s", constants.ErrJSONDecodingResponseBody, err)
-			return constants.ErrJSONDecodingResponseBody
+			return nil, constants.ErrJSONDecodingResponseBody
 		}
 
 		nextToken = nl.NextToken
@@ -225,28 +250,5 @@ func (nt *Tree) fetchFresh() error {
 		}
 	}
 
-	nodeMap := make(map[string]*Node, len(nodes))
-	for _, node := range nodes {
-		if !node.Available() {
-			continue
-		}
-		nt.setClient(node)
-		nodeMap[node.ID] = node
-	}
-
-	for _, node := range nodeMap {
-		if node.Name == "" && node.IsDir() && len(node.Parents) == 0 {
-			nt.Node = node
-			node.Root = true
-		}
-
-		for _, parentID := range node.Parents {
-			if pn, found := nodeMap[parentID]; found {
-				pn.Nodes = append(pn.Nodes, node)
-			}
-		}
-	}
-
-	nt.nodeMap = nodeMap
-	return nil
+	return nodes, nil
 }
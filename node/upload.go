@@ -2,6 +2,8 @@ package node
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,11 +16,13 @@ import (
 
 // CreateFolder creates the named folder under the node
 func (n *Node) CreateFolder(name string) (*Node, error) {
-	cn := &newNode{
-		Name:    name,
-		Kind:    "FOLDER",
-		Parents: []string{n.ID},
-	}
+	return n.CreateFolderWithMetadata(name, nil)
+}
+
+// CreateFolderWithMetadata creates the named folder under the node with the
+// labels and properties of md, which may be nil.
+func (n *Node) CreateFolderWithMetadata(name string, md *Metadata) (*Node, error) {
+	cn := newNodeWithMetadata(name, "FOLDER", n.ID, md)
 	jsonBytes, err := json.Marshal(cn)
 	if err != nil {
 		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
@@ -54,11 +58,29 @@ func (n *Node) CreateFolder(name string) (*Node, error) {
 
 // Upload writes contents of r as name inside the current node.
 func (n *Node) Upload(name string, r io.Reader) (*Node, error) {
-	metadata := &newNode{
-		Name:    name,
-		Kind:    "FILE",
-		Parents: []string{n.ID},
-	}
+	return n.UploadWithMetadata(name, r, nil)
+}
+
+// UploadWithMetadata writes contents of r as name inside the current node with
+// the labels and properties of md, which may be nil.
+func (n *Node) UploadWithMetadata(name string, r io.Reader, md *Metadata) (*Node, error) {
+	return n.UploadWithOptions(name, r, &UploadOptions{Metadata: md})
+}
+
+// UploadOptions are the options of (*Node).UploadWithOptions.
+type UploadOptions struct {
+	// Metadata holds the labels and properties of the new node, it may be nil.
+	Metadata *Metadata
+
+	// Deduplicate lets the server reject a content it already stores, the
+	// upload then returns a *DuplicateError pointing at the existing node.
+	Deduplicate bool
+}
+
+// UploadWithOptions writes contents of r as name inside the current node
+// according to opts.
+func (n *Node) UploadWithOptions(name string, r io.Reader, opts *UploadOptions) (*Node, error) {
+	metadata := newNodeWithMetadata(name, "FILE", n.ID, opts.Metadata)
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
 		log.Errorf("%s: %s", constants.ErrJSONEncoding, err)
@@ -66,33 +88,60 @@ func (n *Node) Upload(name string, r io.Reader) (*Node, error) {
 	}
 
 	postURL := n.client.GetContentURL("nodes?suppress=deduplication")
+	if opts.Deduplicate {
+		postURL = n.client.GetContentURL("nodes")
+	}
 	node, err := n.upload(postURL, "POST", string(metadataJSON), name, r)
-	if err != nil {
+	if err != nil && err != constants.ErrMD5Mismatch {
 		return nil, err
 	}
 
+	// a corrupt node is created all the same, return it so it can be dealt
+	// with.
 	n.AddChild(node)
-	return node, nil
+	return node, err
 }
 
 // Overwrite writes contents of r as name inside the current node.
 func (n *Node) Overwrite(r io.Reader) error {
 	putURL := n.client.GetContentURL(fmt.Sprintf("nodes/%s/content", n.ID))
 	node, err := n.upload(putURL, "PUT", "", n.Name, r)
-	if err != nil {
+	if err != nil && err != constants.ErrMD5Mismatch {
 		return err
 	}
+	if uerr := n.update(node); uerr != nil {
+		return uerr
+	}
+
+	return err
+}
+
+func newNodeWithMetadata(name, kind, parentID string, md *Metadata) *newNode {
+	nn := &newNode{
+		Name:    name,
+		Kind:    kind,
+		Parents: []string{parentID},
+	}
+	if md != nil {
+		nn.Labels = md.Labels
+		nn.Properties = md.Properties
+	}
 
-	return n.update(node)
+	return nn
 }
 
+// upload sends the content of r and returns the node returned by the server.
+// The MD5 of the content is computed while it is sent, if it does not match
+// the MD5 of the returned node, the node is returned along with
+// constants.ErrMD5Mismatch. A conflict is returned as a *DuplicateError.
 func (n *Node) upload(url, method, metadataJSON, name string, r io.Reader) (*Node, error) {
 	bodyReader, bodyWriter := io.Pipe()
 	errChan := make(chan error)
 	bodyChan := make(chan io.ReadCloser)
 	contentTypeChan := make(chan string)
+	md5Chan := make(chan string, 1)
 
-	go n.bodyWriter(metadataJSON, name, r, bodyWriter, errChan, contentTypeChan)
+	go n.bodyWriter(metadataJSON, name, r, bodyWriter, errChan, contentTypeChan, md5Chan)
 	go func() {
 		req, err := http.NewRequest(method, url, bodyReader)
 		if err != nil {
@@ -113,6 +162,13 @@ func (n *Node) upload(url, method, metadataJSON, name string, r io.Reader) (*Nod
 			}
 			return
 		}
+		if res.StatusCode == http.StatusConflict {
+			select {
+			case errChan <- n.duplicateError(res):
+			default:
+			}
+			return
+		}
 		if err := n.client.CheckResponse(res); err != nil {
 			select {
 			case errChan <- err:
@@ -141,12 +197,22 @@ func (n *Node) upload(url, method, metadataJSON, name string, r io.Reader) (*Nod
 				return nil, constants.ErrJSONDecodingResponseBody
 			}
 
+			// the server responds once the whole content was sent, the
+			// digest is known by then.
+			sum := <-md5Chan
+			if node.ContentProperties.MD5 == "" {
+				log.Debugf("the server returned no MD5 for %s, cannot verify the upload", name)
+			} else if node.ContentProperties.MD5 != sum {
+				log.Errorf("%s: %s: sent %s got %s", constants.ErrMD5Mismatch, name, sum, node.ContentProperties.MD5)
+				return &node, constants.ErrMD5Mismatch
+			}
+
 			return &node, nil
 		}
 	}
 }
 
-func (n *Node) bodyWriter(metadataJSON, name string, r io.Reader, bodyWriter io.WriteCloser, errChan chan error, contentTypeChan chan string) {
+func (n *Node) bodyWriter(metadataJSON, name string, r io.Reader, bodyWriter io.WriteCloser, errChan chan error, contentTypeChan chan string, md5Chan chan string) {
 	writer := multipart.NewWriter(bodyWriter)
 	contentTypeChan <- writer.FormDataContentType()
 	if metadataJSON != "" {
@@ -169,7 +235,8 @@ func (n *Node) bodyWriter(metadataJSON, name string, r io.Reader, bodyWriter io.
 		}
 		return
 	}
-	count, err := io.Copy(part, r)
+	hash := md5.New()
+	count, err := io.Copy(io.MultiWriter(part, hash), r)
 	if err != nil {
 		log.Errorf("%s: %s", constants.ErrWritingFileContents, err)
 		select {
@@ -185,6 +252,7 @@ func (n *Node) bodyWriter(metadataJSON, name string, r io.Reader, bodyWriter io.
 		}
 		return
 	}
+	md5Chan <- hex.EncodeToString(hash.Sum(nil))
 
 	select {
 	case errChan <- writer.Close():
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func TestNewNodeWithMetadata(t *testing.T) {
+	tests := []struct {
+		md   *Metadata
+		want string
+	}{
+		{
+			md:   nil,
+			want: `{"name":"a","kind":"FILE","parents":["p"]}`,
+		},
+		{
+			md: &Metadata{
+				Labels:     []string{"l"},
+				Properties: Properties{"owner": {"key": "value"}},
+			},
+			want: `{"name":"a","kind":"FILE","labels":["l"],"properties":{"owner":{"key":"value"}},"parents":["p"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := json.Marshal(newNodeWithMetadata("a", "FILE", "p", test.md))
+		if err != nil {
+			t.Fatalf("json.Marshal() error: %s", err)
+		}
+		if string(got) != test.want {
+			t.Errorf("newNodeWithMetadata(%v): want %s got %s", test.md, test.want, got)
+		}
+	}
+}
+
+func TestUploadVerifiesMD5(t *testing.T) {
+	var corrupt bool
+	c := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("content")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hash := md5.New()
+		io.Copy(hash, f)
+		if corrupt {
+			hash.Write([]byte("corruption"))
+		}
+		fmt.Fprintf(w, `{"id":"new","name":"file","kind":"FILE","contentProperties":{"md5":%q}}`, hex.EncodeToString(hash.Sum(nil)))
+	})
+	defer c.Close()
+	folder := &Node{ID: "folder", Kind: "FOLDER", client: c}
+
+	for _, corrupt = range []bool{false, true} {
+		want := error(nil)
+		if corrupt {
+			want = constants.ErrMD5Mismatch
+		}
+		n, err := folder.Upload("file", strings.NewReader("content"))
+		if err != want {
+			t.Errorf("corrupt %t: folder.Upload() error: want %v got %v", corrupt, want, err)
+		}
+		if n == nil || n.ID != "new" {
+			t.Errorf("corrupt %t: folder.Upload(): want the new node got %v", corrupt, n)
+		}
+		if err := n.Overwrite(strings.NewReader("content")); err != want {
+			t.Errorf("corrupt %t: n.Overwrite() error: want %v got %v", corrupt, want, err)
+		}
+	}
+}
+
+func TestUploadDuplicate(t *testing.T) {
+	var query string
+	c := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		io.Copy(ioutil.Discard, r.Body)
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"code":"","message":"Node with the same content already exists","info":{"nodeId":"existing"}}`)
+	})
+	defer c.Close()
+	existing := &Node{ID: "existing", Name: "other", Kind: "FILE"}
+	root := &Node{ID: "root", Kind: "FOLDER", Root: true, Nodes: Nodes{existing}}
+	c.tree = &Tree{Node: root}
+	c.tree.buildNodeMap(root)
+	folder := &Node{ID: "folder", Kind: "FOLDER", client: c}
+
+	for _, dedupe := range []bool{false, true} {
+		_, err := folder.UploadWithOptions("file", strings.NewReader("content"), &UploadOptions{Deduplicate: dedupe})
+		derr, ok := err.(*DuplicateError)
+		if !ok {
+			t.Fatalf("dedupe %t: folder.UploadWithOptions() error: want a *DuplicateError got %v", dedupe, err)
+		}
+		if derr.ID != "existing" || derr.Node != existing {
+			t.Errorf("dedupe %t: want the existing node got %q %v", dedupe, derr.ID, derr.Node)
+		}
+		if want := !dedupe; strings.Contains(query, "suppress=deduplication") != want {
+			t.Errorf("dedupe %t: query %q", dedupe, query)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// VerifyingReader reads the content of a node and verifies it as it is read.
+// Instead of io.EOF, it returns constants.ErrSizeMismatch if the content is
+// not of the size of the node, or constants.ErrMD5Mismatch if its MD5 differs
+// from the MD5 of the node. The MD5 is not verified if the node has none.
+type VerifyingReader struct {
+	r    io.ReadCloser
+	hash hash.Hash
+	read int64
+	size int64
+	md5  string
+}
+
+// Verify returns a VerifyingReader of rc, the content of the node, e.g. as
+// returned by Download.
+func (n *Node) Verify(rc io.ReadCloser) *VerifyingReader {
+	return &VerifyingReader{
+		r:    rc,
+		hash: md5.New(),
+		size: n.Size(),
+		md5:  n.ContentProperties.MD5,
+	}
+}
+
+// Read reads from the content and verifies it once all of it was read.
+func (vr *VerifyingReader) Read(p []byte) (int, error) {
+	n, err := vr.r.Read(p)
+	vr.hash.Write(p[:n])
+	vr.read += int64(n)
+	if vr.read > vr.size {
+		log.Errorf("%s: want %d bytes got more", constants.ErrSizeMismatch, vr.size)
+		return n, constants.ErrSizeMismatch
+	}
+	if err != io.EOF {
+		return n, err
+	}
+
+	if vr.read != vr.size {
+		log.Errorf("%s: want %d bytes got %d", constants.ErrSizeMismatch, vr.size, vr.read)
+		return n, constants.ErrSizeMismatch
+	}
+	if sum := hex.EncodeToString(vr.hash.Sum(nil)); vr.md5 != "" && sum != vr.md5 {
+		log.Errorf("%s: want %s got %s", constants.ErrMD5Mismatch, vr.md5, sum)
+		return n, constants.ErrMD5Mismatch
+	}
+
+	return n, io.EOF
+}
+
+// Close closes the content.
+func (vr *VerifyingReader) Close() error {
+	return vr.r.Close()
+}
@@ -0,0 +1,34 @@
+package node
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	// md5 of "content"
+	const sum = "9a0364b9e99bb480dd25e1f0284c8555"
+	tests := []struct {
+		content string
+		size    uint64
+		md5     string
+		want    error
+	}{
+		{"content", 7, sum, nil},
+		{"content", 7, "", nil},
+		{"content", 8, sum, constants.ErrSizeMismatch},
+		{"content", 6, sum, constants.ErrSizeMismatch},
+		{"CONTENT", 7, sum, constants.ErrMD5Mismatch},
+	}
+
+	for _, test := range tests {
+		n := &Node{ContentProperties: ContentProperties{Size: test.size, MD5: test.md5}}
+		vr := n.Verify(ioutil.NopCloser(strings.NewReader(test.content)))
+		if _, err := ioutil.ReadAll(vr); err != test.want {
+			t.Errorf("reading %q of size %d and md5 %q: want %v got %v", test.content, test.size, test.md5, test.want, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package node
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	// WalkFunc is the type of the function called by (*Tree).Walk to visit
+	// each node. The path argument is the full remote path of the node,
+	// starting with a slash. If the root could not be found, fn is called once
+	// with a nil node and the error returned by (*Tree).FindNode.
+	//
+	// If the function returns SkipDir when invoked on a folder, Walk skips the
+	// folder's contents. If it returns SkipDir when invoked on a file, Walk
+	// skips the remaining files in the containing folder. If it returns
+	// SkipAll, Walk stops and returns nil. Any other non-nil error stops the
+	// walk and is returned by Walk.
+	WalkFunc func(path string, n *Node, err error) error
+
+	byName Nodes
+)
+
+var (
+	// SkipDir is used as a return value from a WalkFunc to indicate that the
+	// folder named in the call is to be skipped.
+	SkipDir = errors.New("skip this folder")
+
+	// SkipAll is used as a return value from a WalkFunc to indicate that all
+	// remaining nodes are to be skipped.
+	SkipAll = errors.New("skip everything and stop the walk")
+)
+
+// Walk walks the tree rooted at root, calling fn for each node including
+// root. The nodes are walked in lexical order of their lower-cased names,
+// which makes the output deterministic. A node with multiple parents is
+// visited once for every path leading to it, but a node is never visited
+// twice along the same path so a cycle in the parents cannot loop forever.
+func (nt *Tree) Walk(root string, fn WalkFunc) error {
+	root = cleanPath(root)
+	n, err := nt.FindNode(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = nt.walk(root, n, fn, make(map[string]bool))
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+
+	return err
+}
+
+func (nt *Tree) walk(path string, n *Node, fn WalkFunc, ancestors map[string]bool) error {
+	if err := fn(path, n, nil); err != nil || !n.IsDir() {
+		return err
+	}
+
+	ancestors[n.ID] = true
+	defer delete(ancestors, n.ID)
+	for _, child := range n.sortedNodes() {
+		if ancestors[child.ID] {
+			continue
+		}
+		if err := nt.walk(joinPath(path, child.Name), child, fn, ancestors); err != nil {
+			if err == SkipDir {
+				if child.IsDir() {
+					continue
+				}
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortedNodes returns a copy of the children of n sorted by name.
+func (n *Node) sortedNodes() Nodes {
+	nodes := make(Nodes, len(n.Nodes))
+	copy(nodes, n.Nodes)
+	sort.Sort(byName(nodes))
+	return nodes
+}
+
+func (ns byName) Len() int      { return len(ns) }
+func (ns byName) Swap(i, j int) { ns[i], ns[j] = ns[j], ns[i] }
+func (ns byName) Less(i, j int) bool {
+	li, lj := strings.ToLower(ns[i].Name), strings.ToLower(ns[j].Name)
+	if li != lj {
+		return li < lj
+	}
+	if ns[i].Name != ns[j].Name {
+		return ns[i].Name < ns[j].Name
+	}
+	return ns[i].ID < ns[j].ID
+}
+
+// cleanPath returns path with a leading slash, no trailing slash and no
+// repeated slashes.
+func cleanPath(path string) string {
+	re := regexp.MustCompile("/[/]*")
+	path = string(re.ReplaceAll([]byte("/"+path), []byte("/")))
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return path
+}
+
+// joinPath returns the path of the child named name under the folder dir.
+func joinPath(dir, name string) string {
+	if dir == "/" {
+		return dir + name
+	}
+
+	return dir + "/" + name
+}
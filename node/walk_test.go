@@ -0,0 +1,99 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		root string
+		skip map[string]error
+		want []string
+	}{
+		{
+			root: "/",
+			want: []string{"/", "/pictures", "/pictures/logo.png", "/README.md"},
+		},
+		{
+			root: "//pictures/",
+			want: []string{"/pictures", "/pictures/logo.png"},
+		},
+		{
+			root: "/",
+			skip: map[string]error{"/pictures": SkipDir},
+			want: []string{"/", "/pictures", "/README.md"},
+		},
+		{
+			root: "/",
+			skip: map[string]error{"/pictures/logo.png": SkipDir},
+			want: []string{"/", "/pictures", "/pictures/logo.png", "/README.md"},
+		},
+		{
+			root: "/",
+			skip: map[string]error{"/pictures/logo.png": SkipAll},
+			want: []string{"/", "/pictures", "/pictures/logo.png"},
+		},
+	}
+
+	for _, test := range tests {
+		var got []string
+		err := Mocked.Walk(test.root, func(path string, n *Node, err error) error {
+			if err != nil {
+				return err
+			}
+			got = append(got, path)
+			return test.skip[path]
+		})
+		if err != nil {
+			t.Errorf("Mocked.Walk(%q) error: %s", test.root, err)
+		}
+		if !reflect.DeepEqual(test.want, got) {
+			t.Errorf("Mocked.Walk(%q): want %v got %v", test.root, test.want, got)
+		}
+	}
+}
+
+func TestWalkNotFound(t *testing.T) {
+	var called bool
+	err := Mocked.Walk("/not/found", func(path string, n *Node, err error) error {
+		called = true
+		if n != nil {
+			t.Errorf("Mocked.Walk(%q): want a nil node got %v", path, n)
+		}
+		return err
+	})
+	if !called {
+		t.Errorf("Mocked.Walk(%q): the WalkFunc was never called", "/not/found")
+	}
+	if err == nil {
+		t.Errorf("Mocked.Walk(%q): want an error got nil", "/not/found")
+	}
+}
+
+func TestWalkMultipleParents(t *testing.T) {
+	// /a and /b both contain c, and c contains a which would loop forever.
+	var (
+		a    = &Node{ID: "a", Name: "a", Kind: "FOLDER"}
+		b    = &Node{ID: "b", Name: "b", Kind: "FOLDER"}
+		c    = &Node{ID: "c", Name: "c", Kind: "FOLDER"}
+		root = &Node{ID: "root", Kind: "FOLDER", Root: true, Nodes: Nodes{b, a}}
+	)
+	a.Nodes = Nodes{c}
+	b.Nodes = Nodes{c}
+	c.Nodes = Nodes{a}
+	nt := &Tree{Node: root}
+
+	var got []string
+	err := nt.Walk("/", func(path string, n *Node, err error) error {
+		got = append(got, path)
+		return err
+	})
+	if err != nil {
+		t.Errorf("nt.Walk(%q) error: %s", "/", err)
+	}
+	want := []string{"/", "/a", "/a/c", "/b", "/b/c", "/b/c/a"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("nt.Walk(%q): want %v got %v", "/", want, got)
+	}
+}
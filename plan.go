@@ -0,0 +1,373 @@
+package acd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+	"gopkg.in/acd.v0/node"
+)
+
+// PlanAction is what applying a Plan does with one of its files.
+type PlanAction string
+
+const (
+	// PlanCreate transfers a file which does not exist at its target.
+	PlanCreate PlanAction = "create"
+
+	// PlanOverwrite transfers a file over the existing one.
+	PlanOverwrite PlanAction = "overwrite"
+
+	// PlanSkip keeps the existing file.
+	PlanSkip PlanAction = "skip"
+
+	// PlanRename uploads a file next to the existing one, see ConflictRename.
+	PlanRename PlanAction = "rename"
+
+	// PlanFail does not transfer a file, the reason is in PlanFile.Error.
+	PlanFail PlanAction = "fail"
+)
+
+// The directions of a Plan.
+const (
+	PlanDirectionUpload   = "upload"
+	PlanDirectionDownload = "download"
+)
+
+type (
+	// Plan is what a folder transfer does, as computed by (*Client).PlanUpload
+	// and (*Client).PlanDownload without transferring anything. It can be
+	// saved as JSON and applied later by (*Client).ApplyPlan.
+	Plan struct {
+		// Direction is PlanDirectionUpload or PlanDirectionDownload.
+		Direction  string `json:"direction"`
+		LocalPath  string `json:"localPath"`
+		RemotePath string `json:"remotePath"`
+
+		// Folders are the folders to create, remote ones for an upload and
+		// local ones for a download, parents before their children.
+		Folders []string `json:"folders"`
+
+		// Files are the files of the transfer sorted by remote path.
+		Files []PlanFile `json:"files"`
+
+		// Bytes is the size of the files to transfer, SkippedBytes the size
+		// of the files skipped.
+		Bytes        int64 `json:"bytes"`
+		SkippedBytes int64 `json:"skippedBytes"`
+
+		// UploadOptions or DownloadOptions, depending on Direction, are the
+		// options the plan is applied with.
+		UploadOptions   *UploadOptions   `json:"uploadOptions,omitempty"`
+		DownloadOptions *DownloadOptions `json:"downloadOptions,omitempty"`
+	}
+
+	// PlanFile is a file of a Plan.
+	PlanFile struct {
+		Action    PlanAction `json:"action"`
+		LocalPath string     `json:"localPath"`
+
+		// RemotePath is the plain path of the remote file, RenamedPath the
+		// path a file is uploaded to instead with PlanRename.
+		RemotePath  string `json:"remotePath"`
+		RenamedPath string `json:"renamedPath,omitempty"`
+
+		Size int64 `json:"size"`
+
+		// Error is the reason of PlanFail.
+		Error string `json:"error,omitempty"`
+
+		// Remote is the remote file when the plan was made, nil if it did
+		// not exist.
+		Remote *RemoteState `json:"remote,omitempty"`
+	}
+
+	// RemoteState identifies a version of a remote node. The manifest
+	// identifies a file stored in chunks.
+	RemoteState struct {
+		ID      string `json:"id"`
+		Version uint64 `json:"version"`
+		MD5     string `json:"md5,omitempty"`
+	}
+)
+
+// PlanUpload returns the plan of uploading the local folder localPath to
+// remotePath according to opts, see (*Client).UploadFolderWithOptions.
+// Conflicts are resolved as the upload would, the files are only read to
+// compare their content with the existing files.
+func (c *Client) PlanUpload(localPath, remotePath string, opts *UploadOptions) (*Plan, error) {
+	files, folders, err := c.prepareUpload(localPath, remotePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Direction: PlanDirectionUpload, LocalPath: localPath, RemotePath: remotePath, UploadOptions: opts}
+	for _, ft := range files {
+		folders = append(folders, path.Dir(ft.remotePath))
+	}
+	missing := make(map[string]bool)
+	for _, folder := range folders {
+		for dir := folder; !missing[dir]; dir = path.Dir(dir) {
+			if _, err := c.findSilently(dir); err == nil {
+				break
+			}
+			missing[dir] = true
+		}
+	}
+	for _, ft := range files {
+		pf, err := c.planUploadFile(ft, opts)
+		if err != nil {
+			return nil, err
+		}
+		plan.add(pf)
+	}
+	for dir := range missing {
+		plan.Folders = append(plan.Folders, dir)
+	}
+	sort.Strings(plan.Folders)
+	sort.Sort(byRemotePath(plan.Files))
+
+	return plan, nil
+}
+
+// planUploadFile returns the PlanFile of uploading ft.
+func (c *Client) planUploadFile(ft fileTransfer, opts *UploadOptions) (PlanFile, error) {
+	pf := PlanFile{Action: PlanCreate, LocalPath: ft.localPath, RemotePath: ft.remotePath, Size: ft.size}
+	if isSpecial(ft.mode) {
+		pf.Action, pf.Error = PlanFail, constants.ErrSpecialFile.Error()
+		return pf, nil
+	}
+	if ft.node == nil {
+		return pf, nil
+	}
+	pf.Remote = nodeState(ft.node)
+
+	f, err := openLocal(ft)
+	if err != nil {
+		return pf, err
+	}
+	if closer, ok := f.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	policy, err := c.resolveConflict(ft, f, opts, true)
+	switch {
+	case err == constants.ErrFileExists, err == constants.ErrFileExistsWithDifferentContents, err == constants.ErrFileExistsAndIsFolder:
+		pf.Action, pf.Error = PlanFail, err.Error()
+	case err != nil:
+		return pf, err
+	case policy == ConflictSkip:
+		pf.Action = PlanSkip
+	case policy == ConflictOverwrite:
+		pf.Action = PlanOverwrite
+	case policy == ConflictRename:
+		pf.Action, pf.RenamedPath = PlanRename, c.renamedPath(ft.remotePath)
+	}
+
+	return pf, nil
+}
+
+// PlanDownload returns the plan of downloading the remote folder remotePath
+// to localPath according to opts, see (*Client).DownloadFolderWithOptions.
+// The names failing to decrypt are planned as PlanFail.
+func (c *Client) PlanDownload(localPath, remotePath string, opts *DownloadOptions) (*Plan, error) {
+	plan := &Plan{Direction: PlanDirectionDownload, LocalPath: localPath, RemotePath: remotePath, DownloadOptions: opts}
+	files, errs, err := c.walkDownload(localPath, remotePath, opts, func(flp string) error {
+		if _, err := os.Stat(flp); os.IsNotExist(err) {
+			plan.Folders = append(plan.Folders, flp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range errs {
+		plan.add(PlanFile{Action: PlanFail, RemotePath: e.Path, Error: e.Err.Error()})
+	}
+	for _, ft := range files {
+		pf := PlanFile{Action: PlanCreate, LocalPath: ft.localPath, RemotePath: ft.remotePath, Size: ft.size, Remote: nodeState(ft.node)}
+		if _, err := os.Stat(ft.localPath); err == nil {
+			pf.Action = PlanOverwrite
+		}
+		plan.add(pf)
+	}
+	sort.Sort(byRemotePath(plan.Files))
+
+	return plan, nil
+}
+
+// CheckPlan returns constants.ErrPlanOutdated if a remote file of plan
+// changed since it was made, or if the target of a renamed file now exists.
+// The files planned to fail are not checked.
+func (c *Client) CheckPlan(plan *Plan) error {
+	outdated := false
+	for _, pf := range plan.Files {
+		if pf.Action == PlanFail {
+			continue
+		}
+		if !sameState(pf.Remote, c.remoteState(pf.RemotePath)) {
+			log.Errorf("%s: %s", constants.ErrPlanOutdated, pf.RemotePath)
+			outdated = true
+		}
+		if pf.Action == PlanRename && c.remoteState(pf.RenamedPath) != nil {
+			log.Errorf("%s: %s", constants.ErrPlanOutdated, pf.RenamedPath)
+			outdated = true
+		}
+	}
+	if outdated {
+		return constants.ErrPlanOutdated
+	}
+
+	return nil
+}
+
+// ApplyPlan checks plan, see (*Client).CheckPlan, then transfers its files
+// with its options. The files planned to fail and the files failing to
+// transfer are returned as TransferErrors.
+func (c *Client) ApplyPlan(plan *Plan) error {
+	if err := c.CheckPlan(plan); err != nil {
+		return err
+	}
+
+	switch {
+	case plan.Direction == PlanDirectionUpload && plan.UploadOptions != nil:
+		return c.applyUpload(plan)
+	case plan.Direction == PlanDirectionDownload && plan.DownloadOptions != nil:
+		return c.applyDownload(plan)
+	default:
+		log.Errorf("%s: direction %q", constants.ErrInvalidPlan, plan.Direction)
+		return constants.ErrInvalidPlan
+	}
+}
+
+// applyUpload uploads the files of plan.
+func (c *Client) applyUpload(plan *Plan) error {
+	opts := plan.UploadOptions
+	if err := c.checkPreserve(opts); err != nil {
+		return err
+	}
+	files := make([]fileTransfer, len(plan.Files))
+	for i, pf := range plan.Files {
+		files[i] = fileTransfer{localPath: pf.LocalPath, remotePath: pf.RemotePath, size: pf.Size, mode: markerMode(pf.RemotePath)}
+	}
+
+	return c.uploadFiles(files, plan.Folders, opts, func(i int, folder *node.Node, tracker *progressTracker) error {
+		var (
+			ft  = files[i]
+			pf  = plan.Files[i]
+			err error
+		)
+		switch pf.Action {
+		case PlanFail:
+			return errors.New(pf.Error)
+		case PlanSkip:
+			tracker.done(ft)
+			return nil
+		case PlanRename:
+			ft.remotePath = pf.RenamedPath
+		case PlanOverwrite:
+			if ft.node, ft.chunked, err = c.findNode(ft.remotePath); err != nil {
+				return err
+			}
+			if ft, err = c.applyConflict(ft, ConflictOverwrite); err != nil {
+				return err
+			}
+		}
+
+		log.Infof("uploading %q to %q", ft.localPath, ft.remotePath)
+		f, err := openLocal(ft)
+		if err != nil {
+			return err
+		}
+		if closer, ok := f.(io.Closer); ok {
+			defer closer.Close()
+		}
+		if err := c.upload(folder, ft, f, opts, tracker); err != nil {
+			if err != constants.ErrNoContentsToUpload {
+				return err
+			}
+			tracker.done(ft)
+		}
+
+		return nil
+	})
+}
+
+// applyDownload creates the local folders of plan and downloads its files.
+func (c *Client) applyDownload(plan *Plan) error {
+	for _, dir := range plan.Folders {
+		if err := mkdirLocal(dir); err != nil {
+			return err
+		}
+	}
+
+	var (
+		files []fileTransfer
+		errs  TransferErrors
+	)
+	for _, pf := range plan.Files {
+		if pf.Action == PlanFail {
+			errs = append(errs, &TransferError{Path: pf.RemotePath, Err: errors.New(pf.Error)})
+			continue
+		}
+		n, chunked, err := c.findNode(pf.RemotePath)
+		if err != nil {
+			errs = append(errs, &TransferError{Path: pf.RemotePath, Err: err})
+			continue
+		}
+		ft := fileTransfer{node: n, localPath: pf.LocalPath, remotePath: pf.RemotePath, size: pf.Size, chunked: chunked}
+		if plan.DownloadOptions.Symlinks == SymlinkStore {
+			ft.mode = markerMode(pf.RemotePath)
+		}
+		files = append(files, ft)
+	}
+
+	return c.downloadFiles(files, errs, plan.DownloadOptions)
+}
+
+// add adds pf to the files and the totals of plan.
+func (plan *Plan) add(pf PlanFile) {
+	plan.Files = append(plan.Files, pf)
+	switch pf.Action {
+	case PlanSkip:
+		plan.SkippedBytes += pf.Size
+	case PlanCreate, PlanOverwrite, PlanRename:
+		plan.Bytes += pf.Size
+	}
+}
+
+// remoteState returns the state of the file of the plain path p, or of its
+// manifest if it is stored in chunks, nil if it does not exist.
+func (c *Client) remoteState(p string) *RemoteState {
+	n, err := c.findSilently(p)
+	if err != nil {
+		if n, err = c.findSilently(p + ChunkedSuffix); err != nil {
+			return nil
+		}
+	}
+
+	return nodeState(n)
+}
+
+func nodeState(n *node.Node) *RemoteState {
+	return &RemoteState{ID: n.ID, Version: n.Version, MD5: n.ContentProperties.MD5}
+}
+
+func sameState(a, b *RemoteState) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+type byRemotePath []PlanFile
+
+func (fs byRemotePath) Len() int           { return len(fs) }
+func (fs byRemotePath) Swap(i, j int)      { fs[i], fs[j] = fs[j], fs[i] }
+func (fs byRemotePath) Less(i, j int) bool { return fs[i].RemotePath < fs[j].RemotePath }
@@ -0,0 +1,100 @@
+package acd
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestPlanUpload(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	dir, err := ioutil.TempDir("", "acd-plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"README.md":          "new readme",
+		"pictures/logo.png":  "new logo",
+		"music/song.mp3":     "song",
+		"pictures/photo.jpg": "photo",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(path.Dir(path.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		opts    UploadOptions
+		actions map[string]PlanAction
+		bytes   int64
+	}{
+		{
+			UploadOptions{Recursive: true},
+			map[string]PlanAction{"/README.md": PlanFail, "/music/song.mp3": PlanCreate, "/pictures/logo.png": PlanFail, "/pictures/photo.jpg": PlanCreate},
+			9,
+		},
+		{
+			UploadOptions{Recursive: true, Conflict: ConflictSkip},
+			map[string]PlanAction{"/README.md": PlanSkip, "/music/song.mp3": PlanCreate, "/pictures/logo.png": PlanSkip, "/pictures/photo.jpg": PlanCreate},
+			9,
+		},
+		{
+			UploadOptions{Conflict: ConflictRename},
+			map[string]PlanAction{"/README.md": PlanRename},
+			10,
+		},
+	}
+
+	for i, test := range tests {
+		plan, err := c.PlanUpload(dir, "/", &test.opts)
+		if err != nil {
+			t.Fatalf("#%d: c.PlanUpload() error: %s", i, err)
+		}
+		if len(plan.Files) != len(test.actions) {
+			t.Errorf("#%d: c.PlanUpload() files: want %d got %d", i, len(test.actions), len(plan.Files))
+		}
+		for _, pf := range plan.Files {
+			if want := test.actions[pf.RemotePath]; want != pf.Action {
+				t.Errorf("#%d: c.PlanUpload() action of %s: want %s got %s", i, pf.RemotePath, want, pf.Action)
+			}
+		}
+		if plan.Bytes != test.bytes {
+			t.Errorf("#%d: c.PlanUpload() bytes: want %d got %d", i, test.bytes, plan.Bytes)
+		}
+		if err := c.CheckPlan(plan); err != nil {
+			t.Errorf("#%d: c.CheckPlan() error: %s", i, err)
+		}
+	}
+
+	plan, err := c.PlanUpload(dir, "/", &UploadOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/music"}; len(plan.Folders) != 1 || plan.Folders[0] != want[0] {
+		t.Errorf("c.PlanUpload() folders: want %v got %v", want, plan.Folders)
+	}
+	for i := range plan.Files {
+		if plan.Files[i].Remote != nil {
+			plan.Files[i].Remote.Version++
+			break
+		}
+	}
+	if err := c.CheckPlan(plan); err != nil {
+		t.Errorf("c.CheckPlan() of a plan with a failure only: want nil got %s", err)
+	}
+	plan.Files[0].Action = PlanOverwrite
+	if err := c.CheckPlan(plan); err != constants.ErrPlanOutdated {
+		t.Errorf("c.CheckPlan() of an outdated plan: want %s got %v", constants.ErrPlanOutdated, err)
+	}
+}
@@ -0,0 +1,200 @@
+package acd
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"gopkg.in/acd.v0/internal/constants"
+)
+
+type (
+	// Progress is the state of a transfer, reported to a ProgressFunc every
+	// time some bytes of a file are transferred and every time a file is
+	// done.
+	Progress struct {
+		// Path is the remote path of the file which progressed.
+		Path string
+
+		// FileBytes is the number of bytes of the file transferred so far and
+		// FileSize its size.
+		FileBytes, FileSize int64
+
+		// Files is the number of files done and TotalFiles the number of files
+		// of the transfer.
+		Files, TotalFiles int
+
+		// Bytes is the number of bytes transferred so far and TotalBytes the
+		// size of all the files of the transfer.
+		Bytes, TotalBytes int64
+
+		// Rate is the number of bytes transferred per second, smoothed
+		// exponentially over about the last rateWindow so that it follows
+		// the current speed. The skipped files do not count.
+		Rate float64
+
+		// ETA is the estimated time left until the end of the transfer, zero
+		// when unknown.
+		ETA time.Duration
+	}
+
+	// ProgressFunc is the type of the function called to report the progress
+	// of a transfer. It is never called concurrently, even by the workers of
+	// a folder transfer.
+	ProgressFunc func(p Progress)
+
+	// progressTracker computes the Progress of a transfer. The methods of a
+	// nil tracker do nothing so a transfer without a ProgressFunc needs no
+	// special case.
+	progressTracker struct {
+		mu        sync.Mutex
+		fn        ProgressFunc
+		fileBytes map[string]int64
+		p         Progress
+
+		// transferred is the number of bytes actually transferred, which
+		// was sampledBytes at sampled when the rate was last updated.
+		transferred  int64
+		sampledBytes int64
+		sampled      time.Time
+	}
+
+	progressReader struct {
+		r       io.Reader
+		tracker *progressTracker
+		ft      fileTransfer
+		offset  int64
+		read    int64
+	}
+)
+
+func newProgressTracker(fn ProgressFunc, files []fileTransfer) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	t := &progressTracker{
+		fn:        fn,
+		fileBytes: make(map[string]int64, len(files)),
+		sampled:   time.Now(),
+	}
+	t.p.TotalFiles = len(files)
+	for _, ft := range files {
+		t.p.TotalBytes += ft.size
+	}
+
+	return t
+}
+
+const (
+	// rateWindow is the time over which the transfer rate is averaged.
+	rateWindow = 10 * time.Second
+
+	// rateInterval is the shortest time between two samples of the rate.
+	rateInterval = 500 * time.Millisecond
+)
+
+// set records that written bytes of the file ft were transferred.
+func (t *progressTracker) set(ft fileTransfer, written int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.p.Bytes += written - t.fileBytes[ft.remotePath]
+	t.transferred += written - t.fileBytes[ft.remotePath]
+	t.fileBytes[ft.remotePath] = written
+	t.report(ft, written)
+}
+
+// done records that the file ft was transferred, or skipped.
+func (t *progressTracker) done(ft fileTransfer) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// a skipped file counts as transferred.
+	t.p.Bytes += ft.size - t.fileBytes[ft.remotePath]
+	t.fileBytes[ft.remotePath] = ft.size
+	t.p.Files++
+	t.report(ft, ft.size)
+}
+
+// reader returns a reader of r recording the bytes read as transferred bytes
+// of the file ft.
+func (t *progressTracker) reader(ft fileTransfer, r io.Reader) io.Reader {
+	return t.readerFrom(ft, r, 0)
+}
+
+// readerFrom returns a reader of r, the content of the file ft from offset,
+// recording the bytes read as transferred bytes of ft, e.g. for a chunk.
+func (t *progressTracker) readerFrom(ft fileTransfer, r io.Reader, offset int64) io.Reader {
+	if t == nil {
+		return r
+	}
+
+	return &progressReader{r: r, tracker: t, ft: ft, offset: offset}
+}
+
+func (t *progressTracker) report(ft fileTransfer, written int64) {
+	t.p.Path = ft.remotePath
+	t.p.FileBytes = written
+	t.p.FileSize = ft.size
+	t.updateRate(time.Now())
+	t.p.ETA = 0
+	if t.p.Rate > 0 && t.p.TotalBytes > t.p.Bytes {
+		t.p.ETA = time.Duration(float64(t.p.TotalBytes-t.p.Bytes) / t.p.Rate * float64(time.Second))
+	}
+	t.fn(t.p)
+}
+
+// updateRate samples the bytes transferred since the last sample at now, if
+// it is at least rateInterval ago, into the smoothed rate. A sample weighs
+// more the longer it covers, so the rate catches up at once after a stall.
+func (t *progressTracker) updateRate(now time.Time) {
+	elapsed := now.Sub(t.sampled)
+	if elapsed < rateInterval {
+		return
+	}
+	rate := float64(t.transferred-t.sampledBytes) / elapsed.Seconds()
+	if rate < 0 {
+		// a retried file restarted from an earlier offset.
+		rate = 0
+	}
+	weight := 1 - math.Exp(-elapsed.Seconds()/rateWindow.Seconds())
+	if t.p.Rate == 0 {
+		weight = 1
+	}
+	t.p.Rate += weight * (rate - t.p.Rate)
+	t.sampledBytes, t.sampled = t.transferred, now
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.read += int64(n)
+		pr.tracker.set(pr.ft, pr.offset+pr.read)
+	}
+
+	return n, err
+}
+
+// Seek seeks the underlying reader if it can, restarting the progress of the
+// file from the new offset, e.g. when an upload is retried.
+func (pr *progressReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := pr.r.(io.Seeker)
+	if !ok {
+		return 0, constants.ErrInvalidSeek
+	}
+	offset, err := seeker.Seek(offset, whence)
+	if err != nil {
+		return offset, err
+	}
+	pr.read = offset
+	pr.tracker.set(pr.ft, pr.offset+offset)
+
+	return offset, nil
+}
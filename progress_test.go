@@ -0,0 +1,70 @@
+package acd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	var (
+		a       = fileTransfer{remotePath: "/a", size: 10}
+		b       = fileTransfer{remotePath: "/b", size: 20}
+		reports []Progress
+	)
+	tracker := newProgressTracker(func(p Progress) { reports = append(reports, p) }, []fileTransfer{a, b})
+
+	ioutil.ReadAll(tracker.reader(a, strings.NewReader("0123456789")))
+	tracker.done(a)
+	// b is skipped, it counts as transferred.
+	tracker.done(b)
+
+	last := reports[len(reports)-1]
+	if want, got := int64(30), last.Bytes; want != got {
+		t.Errorf("Bytes: want %d got %d", want, got)
+	}
+	if want, got := int64(30), last.TotalBytes; want != got {
+		t.Errorf("TotalBytes: want %d got %d", want, got)
+	}
+	if want, got := 2, last.Files; want != got {
+		t.Errorf("Files: want %d got %d", want, got)
+	}
+	if last.ETA != 0 {
+		t.Errorf("ETA: want 0 once done got %s", last.ETA)
+	}
+	for _, p := range reports {
+		if p.Path == "/a" && p.FileBytes > p.FileSize {
+			t.Errorf("FileBytes of /a: want at most %d got %d", p.FileSize, p.FileBytes)
+		}
+	}
+
+	// a nil tracker does nothing.
+	var nilTracker *progressTracker
+	nilTracker.done(a)
+	if r := strings.NewReader(""); nilTracker.reader(a, r) != r {
+		t.Errorf("nilTracker.reader(): want the reader itself")
+	}
+}
+
+func TestProgressRate(t *testing.T) {
+	tracker := newProgressTracker(func(Progress) {}, nil)
+	start := tracker.sampled
+
+	// 1 MB/s for 10 seconds.
+	for i := 1; i <= 10; i++ {
+		tracker.transferred += 1 << 20
+		tracker.updateRate(start.Add(time.Duration(i) * time.Second))
+	}
+	if rate := tracker.p.Rate; rate < 0.99*(1<<20) || rate > 1.01*(1<<20) {
+		t.Errorf("rate at 1 MB/s: want about %d got %.0f", 1<<20, rate)
+	}
+
+	// a stall of a minute, then 1 KB in a second.
+	tracker.updateRate(start.Add(70 * time.Second))
+	tracker.transferred += 1 << 10
+	tracker.updateRate(start.Add(71 * time.Second))
+	if rate := tracker.p.Rate; rate > 1<<14 {
+		t.Errorf("rate after a stall: want less than %d got %.0f", 1<<14, rate)
+	}
+}
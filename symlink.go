@@ -0,0 +1,180 @@
+package acd
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/acd.v0/internal/constants"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+// SymlinkSuffix is appended to the name of a symbolic link stored as a
+// marker, see SymlinkStore. The content of the marker is the target of the
+// link, which is also recorded in the PropertySymlink property when
+// Config.PropertiesOwner is set.
+const SymlinkSuffix = ".acdsymlink"
+
+// PropertySymlink is the property of a marker recording the target of the
+// symbolic link, owned by Config.PropertiesOwner.
+const PropertySymlink = "symlink"
+
+// SymlinkPolicy is what a folder upload does with the symbolic links, see
+// UploadOptions.Symlinks, and what a folder download does with their
+// markers, see DownloadOptions.Symlinks.
+type SymlinkPolicy int
+
+const (
+	// SymlinkFollow uploads the file or the folder a link points to as if it
+	// was in place of the link. The broken links, and the links to a folder
+	// being uploaded, are skipped. A download keeps the markers as they are,
+	// plain files.
+	SymlinkFollow SymlinkPolicy = iota
+
+	// SymlinkSkip skips the links, or the markers.
+	SymlinkSkip
+
+	// SymlinkStore uploads a marker named after the link followed by
+	// SymlinkSuffix, which a download restores as the link, provided its
+	// target is relative without any .. element so it cannot point outside
+	// of the downloaded folder, see DownloadOptions.Symlinks.
+	SymlinkStore
+)
+
+// isSpecial returns whether mode is neither a regular file, a folder nor a
+// symbolic link, e.g. a socket, a device or a named pipe.
+func isSpecial(mode os.FileMode) bool {
+	return !mode.IsRegular() && !mode.IsDir() && mode&os.ModeSymlink == 0
+}
+
+// markerMode returns os.ModeSymlink if the remote path p is a marker, see
+// SymlinkStore.
+func markerMode(p string) os.FileMode {
+	if strings.HasSuffix(p, SymlinkSuffix) {
+		return os.ModeSymlink
+	}
+
+	return 0
+}
+
+// openLocal opens the content to upload of the local file ft, which is the
+// target of a symbolic link stored as a marker. The content must be closed if
+// it implements io.Closer.
+func openLocal(ft fileTransfer) (io.ReadSeeker, error) {
+	if ft.mode&os.ModeSymlink == 0 {
+		f, err := os.Open(ft.localPath)
+		if err != nil {
+			log.Errorf("%s: %s", constants.ErrOpenFile, ft.localPath)
+			return nil, constants.ErrOpenFile
+		}
+		return f, nil
+	}
+
+	target, err := os.Readlink(ft.localPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrOpenFile, err)
+		return nil, constants.ErrOpenFile
+	}
+
+	return strings.NewReader(target), nil
+}
+
+// recordSymlink records the target of the symbolic link ft in the properties
+// of its marker, if Config.PropertiesOwner is set.
+func (c *Client) recordSymlink(ft fileTransfer) error {
+	owner := c.PropertiesOwner()
+	if owner == "" {
+		return nil
+	}
+	target, err := os.Readlink(ft.localPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrOpenFile, err)
+		return constants.ErrOpenFile
+	}
+	n, err := c.findSilently(ft.remotePath)
+	if err != nil {
+		return nil
+	}
+	if current, found := n.Property(owner, PropertySymlink); found && current == target {
+		return nil
+	}
+
+	return n.SetProperty(owner, PropertySymlink, target)
+}
+
+// restoreSymlink creates the symbolic link ft.localPath recorded by the
+// marker ft.node, replacing the existing file but not a folder. The target
+// must be relative and must not climb up, see climbsOut.
+func (c *Client) restoreSymlink(ft fileTransfer) error {
+	target, found := ft.node.Property(c.PropertiesOwner(), PropertySymlink)
+	if !found {
+		r, err := c.Download(ft.remotePath)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			log.Errorf("%s: %s", constants.ErrReadingResponseBody, err)
+			return constants.ErrReadingResponseBody
+		}
+		target = string(content)
+	}
+	if climbsOut(target) {
+		log.Errorf("%s: %s -> %s", constants.ErrSymlinkOutsideFolder, ft.localPath, target)
+		return constants.ErrSymlinkOutsideFolder
+	}
+
+	if info, err := os.Lstat(ft.localPath); err == nil {
+		if info.IsDir() {
+			log.Errorf("%s: %s", constants.ErrFileExistsAndIsFolder, ft.localPath)
+			return constants.ErrFileExistsAndIsFolder
+		}
+		if err := os.Remove(ft.localPath); err != nil {
+			log.Errorf("%s: %s", constants.ErrCreateFile, err)
+			return constants.ErrCreateFile
+		}
+	}
+	if err := os.Symlink(target, ft.localPath); err != nil {
+		log.Errorf("%s: %s", constants.ErrCreateFile, err)
+		return constants.ErrCreateFile
+	}
+
+	return nil
+}
+
+// climbsOut returns whether the target of a link is absolute or has a ..
+// element, even one path.Clean would remove as a link may be followed before
+// it. The other targets point below the folder of the link, whatever the links
+// restored before or at the same time, so they cannot escape from the
+// downloaded folder.
+func climbsOut(target string) bool {
+	if path.IsAbs(target) {
+		return true
+	}
+	for _, element := range strings.Split(target, "/") {
+		if element == ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mkdirLocal creates the local folder name of a download unless it exists,
+// the umask applying to its mode. An existing symbolic link is refused, the
+// files of the folder would be written wherever it points.
+func mkdirLocal(name string) error {
+	if info, err := os.Lstat(name); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		log.Errorf("%s: %s is a symbolic link", constants.ErrCreateFolder, name)
+		return constants.ErrCreateFolder
+	}
+	if err := os.Mkdir(name, os.FileMode(0777)); err != nil && !os.IsExist(err) {
+		log.Errorf("%s: %s", constants.ErrCreateFolder, err)
+		return constants.ErrCreateFolder
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package acd
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"gopkg.in/acd.v0/filter"
+	"gopkg.in/acd.v0/internal/log"
+)
+
+type (
+	// TransferError is the error of a single file of a folder transfer.
+	TransferError struct {
+		Path string
+		Err  error
+	}
+
+	// TransferErrors are the errors of the files of a folder transfer which
+	// failed, the other files were transferred. They are sorted by path.
+	TransferErrors []*TransferError
+)
+
+func (e *TransferError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (es TransferErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+
+	return fmt.Sprintf("%d files failed, the first one being %s", len(es), es[0])
+}
+
+// parallel calls fn with every index from 0 to count-1 on up to jobs
+// goroutines and returns the errors at the index of the call which returned
+// them.
+func parallel(jobs, count int, fn func(i int) error) []error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	errs := make([]error, count)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for j := 0; j < jobs && j < count; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
+
+// folderFilter applies a filter.Filter to the walk of a folder transfer,
+// reading the ignore file of every folder selected with open. The methods of
+// a nil folderFilter select everything.
+type folderFilter struct {
+	filter  *filter.Filter
+	open    func(rel string) (io.ReadCloser, error)
+	folders map[string]*filter.Filter
+}
+
+// newFolderFilter returns the folderFilter of f, nil if f is nil. open returns
+// the file of the path rel relative to the root of the transfer, a nil
+// io.ReadCloser if it does not exist.
+func newFolderFilter(f *filter.Filter, open func(rel string) (io.ReadCloser, error)) (*folderFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	ff := &folderFilter{filter: f, open: open, folders: make(map[string]*filter.Filter)}
+	if err := ff.enter(".", f); err != nil {
+		return nil, err
+	}
+
+	return ff, nil
+}
+
+// match returns whether the file, or the folder if isDir is set, of the path
+// rel relative to the root of the transfer is selected. The ignore file of a
+// selected folder is read.
+func (ff *folderFilter) match(rel string, isDir bool, size int64, modTime time.Time) (bool, error) {
+	if ff == nil {
+		return true, nil
+	}
+	f, found := ff.folders[path.Dir(rel)]
+	if !found {
+		f = ff.filter
+	}
+	if !f.Match(rel, isDir, size, modTime) {
+		log.Debugf("%q is filtered out, skipping", rel)
+		return false, nil
+	}
+	if isDir {
+		return true, ff.enter(rel, f)
+	}
+
+	return true, nil
+}
+
+// enter records the filter of the folder dir, f along with the rules of its
+// ignore file.
+func (ff *folderFilter) enter(dir string, f *filter.Filter) error {
+	ff.folders[dir] = f
+	if f.IgnoreFile == "" {
+		return nil
+	}
+	r, err := ff.open(path.Join(dir, f.IgnoreFile))
+	if err != nil || r == nil {
+		return err
+	}
+	defer r.Close()
+	if ff.folders[dir], err = f.WithRules(dir, r); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package acd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	var (
+		mu             sync.Mutex
+		running, peak  int
+		errOdd         = errors.New("odd")
+		jobs, count    = 3, 20
+		expectedErrors = count / 2
+	)
+	errs := parallel(jobs, count, func(i int) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if i%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+
+	if peak > jobs {
+		t.Errorf("parallel(%d, %d): want at most %d concurrent calls got %d", jobs, count, jobs, peak)
+	}
+	var got int
+	for i, err := range errs {
+		if (i%2 == 1) != (err == errOdd) {
+			t.Errorf("parallel(%d, %d): error at %d: got %v", jobs, count, i, err)
+		}
+		if err != nil {
+			got++
+		}
+	}
+	if got != expectedErrors {
+		t.Errorf("parallel(%d, %d): want %d errors got %d", jobs, count, expectedErrors, got)
+	}
+}
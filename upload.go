@@ -4,51 +4,161 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
+	"sort"
 
+	"gopkg.in/acd.v0/filter"
 	"gopkg.in/acd.v0/internal/constants"
 	"gopkg.in/acd.v0/internal/log"
 	"gopkg.in/acd.v0/node"
 )
 
+// DeduplicatePolicy is what an upload does when the server already stores its
+// content, see UploadOptions.Deduplicate.
+type DeduplicatePolicy int
+
+const (
+	// DeduplicateNone does not let the server deduplicate, the content is
+	// uploaded again.
+	DeduplicateNone DeduplicatePolicy = iota
+
+	// DeduplicateFail returns the *node.DuplicateError.
+	DeduplicateFail
+
+	// DeduplicateSkip treats the file as already uploaded, nothing is created
+	// in the target folder.
+	DeduplicateSkip
+
+	// DeduplicateLink adds the target folder as a parent of the existing
+	// node, see (*node.Node).AddParent. The node keeps its name, which may
+	// differ from the name of the uploaded file.
+	DeduplicateLink
+)
+
+// UploadOptions are the options of (*Client).UploadWithOptions and
+// (*Client).UploadFolderWithOptions.
+type UploadOptions struct {
+	// Overwrite replaces the contents of the existing files, see
+	// ConflictDefault.
+	Overwrite bool
+
+	// Conflict is the policy applying when a file already exists, and
+	// FolderConflict when a folder exists instead of a file, in which case
+	// the upload fails by default.
+	Conflict, FolderConflict ConflictPolicy
+
+	// Recursive uploads the sub-folders of a folder as well.
+	Recursive bool
+
+	// Labels are added to every uploaded file.
+	Labels []string
+
+	// Jobs is the number of files hashed and uploaded at the same time by
+	// (*Client).UploadFolderWithOptions, one when zero.
+	Jobs int
+
+	// Progress, unless nil, is called with the progress of the upload.
+	Progress ProgressFunc `json:"-"`
+
+	// Retries is the number of times a file whose uploaded content does not
+	// match its MD5 is uploaded again. Only the readers which can seek, such
+	// as files, can be uploaded again.
+	Retries int
+
+	// TrashCorrupt trashes the uploaded nodes whose content does not match
+	// once the retries are exhausted.
+	TrashCorrupt bool
+
+	// ChunkSize, unless zero, stores the files larger than ChunkSize bytes as
+	// chunks of ChunkSize bytes and a manifest, see ChunkedSuffix. Only the
+	// readers whose size is known and which implement io.ReaderAt, such as
+	// files, are chunked.
+	ChunkSize int64
+
+	// Filter, unless nil, selects the files uploaded by
+	// (*Client).UploadFolderWithOptions.
+	Filter *filter.Filter `json:"-"`
+
+	// Symlinks is what (*Client).UploadFolderWithOptions does with the
+	// symbolic links, it follows them by default.
+	Symlinks SymlinkPolicy
+
+	// ReportSpecial returns the sockets, the devices and the named pipes of
+	// a folder upload as TransferErrors, they are skipped otherwise.
+	ReportSpecial bool
+
+	// Preserve records the modification time, the mode, the owner and the
+	// group of the uploaded local files in the properties of their nodes, as
+	// well as their extended attributes if Xattrs is set. It requires
+	// Config.PropertiesOwner, see PropertyModTime.
+	Preserve, Xattrs bool
+
+	// Deduplicate, unless DeduplicateNone, lets the server reject the new
+	// files whose content it already stores and resolves the duplicates
+	// according to the policy. The chunks of a chunked file are never
+	// deduplicated.
+	Deduplicate DeduplicatePolicy
+}
+
 // Upload uploads io.Reader to the path defined by the filename. It will create
 // any non-existing folders.
 func (c *Client) Upload(filename string, overwrite bool, r io.Reader) error {
-	var (
-		err      error
-		logLevel = log.GetLevel()
-		fileNode *node.Node
-		node     *node.Node
-	)
+	return c.UploadWithOptions(filename, r, &UploadOptions{Overwrite: overwrite})
+}
 
-	node, err = c.NodeTree.MkdirAll(path.Dir(filename))
+// UploadWithOptions uploads io.Reader to the path defined by the filename
+// according to opts. It will create any non-existing folders. The content is
+// encrypted if filename is under the encrypted folder, see EncryptionConfig.
+func (c *Client) UploadWithOptions(filename string, r io.Reader, opts *UploadOptions) error {
+	if err := c.checkPreserve(opts); err != nil {
+		return err
+	}
+	folder, err := c.NodeTree.MkdirAll(c.encryptPath(path.Dir(filename)))
 	if err != nil {
 		return err
 	}
-	{
-		log.SetLevel(log.DisableLogLevel)
-		fileNode, err = c.NodeTree.FindNode(filename)
-		log.SetLevel(logLevel)
+	// the size, the modification time and the attributes are known when
+	// uploading a file.
+	ft := fileTransfer{remotePath: filename}
+	if stater, ok := r.(interface {
+		Stat() (os.FileInfo, error)
+	}); ok {
+		if stat, err := stater.Stat(); err == nil {
+			ft.size, ft.modTime = stat.Size(), stat.ModTime()
+		}
+	}
+	if f, ok := r.(*os.File); ok {
+		ft.localPath = f.Name()
 	}
-	if err == nil {
-		if !overwrite {
-			log.Errorf("%s: %s", constants.ErrFileExists, filename)
-			return constants.ErrFileExists
+	tracker := newProgressTracker(opts.Progress, []fileTransfer{ft})
+
+	// the file may be stored in chunks, it is replaced all the same.
+	if ft.node, err = c.findSilently(filename); err != nil {
+		ft.node, err = c.findSilently(filename + ChunkedSuffix)
+		ft.chunked = err == nil
+	}
+	if err != nil {
+		ft.node = nil
+	}
+	if ft.node != nil {
+		rs, _ := r.(io.ReadSeeker)
+		policy, err := c.resolveConflict(ft, rs, opts, false)
+		if err != nil {
+			return err
 		}
-		if err = fileNode.Overwrite(r); err != nil {
+		if ft, err = c.applyConflict(ft, policy); err != nil {
 			return err
 		}
-
-		return nil
-	}
-	if _, err = node.Upload(path.Base(filename), r); err != nil {
-		return err
+		if policy == ConflictSkip {
+			tracker.done(ft)
+			return nil
+		}
 	}
 
-	return nil
+	return c.upload(folder, ft, r, opts, tracker)
 }
 
 // UploadFolder uploads an entire folder.
@@ -56,85 +166,417 @@ func (c *Client) Upload(filename string, overwrite bool, r io.Reader) error {
 // localPath.  If overwrite is false and an existing file with the same md5 was
 // found, an error will be returned.
 func (c *Client) UploadFolder(localPath, remotePath string, recursive, overwrite bool) error {
+	return c.UploadFolderWithOptions(localPath, remotePath, &UploadOptions{Recursive: recursive, Overwrite: overwrite})
+}
+
+// UploadFolderWithOptions uploads an entire folder according to opts. The
+// remote folders are created first, then the files are hashed and uploaded by
+// opts.Jobs workers. A file failing to upload does not stop the others, the
+// failures are returned as TransferErrors.
+func (c *Client) UploadFolderWithOptions(localPath, remotePath string, opts *UploadOptions) error {
 	log.Debugf("uploading %q to %q", localPath, remotePath)
-	if err := filepath.Walk(localPath, c.uploadFolderFunc(localPath, remotePath, recursive, overwrite)); err != nil {
+	if err := c.checkPreserve(opts); err != nil {
+		return err
+	}
+	files, folders, err := c.prepareUpload(localPath, remotePath, opts)
+	if err != nil {
 		return err
 	}
 
+	return c.uploadFiles(files, folders, opts, func(i int, folder *node.Node, tracker *progressTracker) error {
+		return c.uploadFile(files[i], folder, opts, tracker)
+	})
+}
+
+// uploadFiles creates the remote folders dirs, even empty, and the folders of
+// files, then calls upload with the index of every file and its folder on
+// opts.Jobs workers. The failures are returned as TransferErrors.
+func (c *Client) uploadFiles(files []fileTransfer, dirs []string, opts *UploadOptions, upload func(i int, folder *node.Node, tracker *progressTracker) error) error {
+	// create every folder once, before the workers need them.
+	var (
+		folders    = make(map[string]*node.Node)
+		folderErrs = make(map[string]error)
+	)
+	for _, f := range files {
+		dirs = append(dirs, path.Dir(f.remotePath))
+	}
+	for _, dir := range dirs {
+		if _, found := folders[dir]; found {
+			continue
+		}
+		folders[dir], folderErrs[dir] = c.NodeTree.MkdirAll(c.encryptPath(dir))
+	}
+
+	var (
+		errs    TransferErrors
+		tracker = newProgressTracker(opts.Progress, files)
+	)
+	uploadErrs := parallel(opts.Jobs, len(files), func(i int) error {
+		dir := path.Dir(files[i].remotePath)
+		if err := folderErrs[dir]; err != nil {
+			return err
+		}
+		return upload(i, folders[dir], tracker)
+	})
+	for i, err := range uploadErrs {
+		if err != nil {
+			errs = append(errs, &TransferError{Path: files[i].localPath, Err: err})
+		}
+		delete(folderErrs, path.Dir(files[i].remotePath))
+	}
+	// the folders without files failing to be created are not reported yet.
+	for dir, err := range folderErrs {
+		if err != nil {
+			errs = append(errs, &TransferError{Path: dir, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		sort.Sort(byTransferPath(errs))
+		return errs
+	}
+
 	return nil
 }
 
-func (c *Client) uploadFolderFunc(localPath, remoteBasePath string, recursive, overwrite bool) filepath.WalkFunc {
-	return func(fpath string, info os.FileInfo, err error) error {
-		var (
-			logLevel   = log.GetLevel()
-			fileNode   *node.Node
-			remoteNode *node.Node
-			f          *os.File
-		)
-
-		parts := strings.SplitAfter(fpath, localPath)
-		remoteFilename := remoteBasePath + strings.Join(parts[1:], "/")
-		remotePath := path.Dir(remoteFilename)
-		log.Debugf("localPath %q remotePath %q fpath %q remoteFilename %q recursive %t overwrite %t",
-			localPath, remotePath, fpath, remoteFilename, recursive, overwrite)
-
-		// is this a folder?
-		if info.IsDir() {
-			log.Debugf("%q is a folder, skipping", fpath)
+// prepareUpload returns the files of the local folder localPath selected by
+// opts.Filter, which may be nil, to upload to remoteBasePath, along with the
+// remote nodes they would replace, and the remote folders to create. The
+// symbolic links are handled according to opts.Symlinks.
+func (c *Client) prepareUpload(localPath, remoteBasePath string, opts *UploadOptions) ([]fileTransfer, []string, error) {
+	ff, err := newFolderFilter(opts.Filter, func(rel string) (io.ReadCloser, error) {
+		file, err := os.Open(filepath.Join(localPath, filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			log.Errorf("%s: %s", constants.ErrOpenFile, err)
+			return nil, constants.ErrOpenFile
+		}
+		return file, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		files   []fileTransfer
+		folders []string
+		// walking is the set of the real paths of the folders being walked,
+		// a link to one of them would loop.
+		walking = make(map[string]bool)
+		walk    func(fpath, rel string, info os.FileInfo) error
+	)
+	walk = func(fpath, rel string, info os.FileInfo) error {
+		remoteFilename := path.Join(remoteBasePath, rel)
+		if info.Mode()&os.ModeSymlink != 0 {
+			switch opts.Symlinks {
+			case SymlinkSkip:
+				log.Debugf("%q is a symbolic link, skipping", fpath)
+				return nil
+			case SymlinkStore:
+				files = append(files, c.uploadTransfer(fpath, remoteFilename+SymlinkSuffix, info))
+				return nil
+			}
+			target, err := os.Stat(fpath)
+			if err != nil {
+				log.Infof("%q is a broken symbolic link, skipping", fpath)
+				return nil
+			}
+			info = target
+			// the link was matched as a file, the folder rules apply now.
+			if info.IsDir() {
+				selected, err := ff.match(rel, true, info.Size(), info.ModTime())
+				if err != nil || !selected {
+					return err
+				}
+			}
+		}
+		switch {
+		case isSpecial(info.Mode()) && !opts.ReportSpecial:
+			log.Infof("%q is not a regular file, skipping", fpath)
 			return nil
+		case !info.IsDir():
+			log.Debugf("localPath %q fpath %q remoteFilename %q", localPath, fpath, remoteFilename)
+			files = append(files, c.uploadTransfer(fpath, remoteFilename, info))
+			return nil
+		// are we not recursive and entering a sub-folder?
+		case rel != "." && !opts.Recursive:
+			log.Debugf("%q is a sub-folder but we are not running recursively, skipping", fpath)
+			return nil
+		}
+
+		real, err := filepath.EvalSymlinks(fpath)
+		if err != nil {
+			log.Errorf("%s: %s", constants.ErrStatFile, err)
+			return constants.ErrStatFile
 		}
-		// are we not recursive and trying to upload a file down the tree?
-		if !recursive && localPath != path.Dir(fpath) {
-			log.Debugf("%q is inside a sub-folder but we are not running recursively, skipping")
+		if walking[real] {
+			log.Infof("%q links to a folder being uploaded, skipping", fpath)
 			return nil
 		}
+		walking[real] = true
+		defer delete(walking, real)
+
+		folders = append(folders, remoteFilename)
+		infos, err := ioutil.ReadDir(fpath)
+		if err != nil {
+			log.Errorf("%s: %s", constants.ErrStatFile, err)
+			return constants.ErrStatFile
+		}
+		for _, info := range infos {
+			childRel := path.Join(rel, info.Name())
+			selected, err := ff.match(childRel, info.IsDir(), info.Size(), info.ModTime())
+			if err != nil {
+				return err
+			}
+			if !selected {
+				continue
+			}
+			if err := walk(filepath.Join(fpath, info.Name()), childRel, info); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		log.Errorf("%s: %s", constants.ErrStatFile, err)
+		return nil, nil, constants.ErrStatFile
+	}
+	if err := walk(localPath, ".", info); err != nil {
+		return nil, nil, err
+	}
+
+	return files, folders, nil
+}
+
+// uploadTransfer returns the transfer of the local file fpath of info to
+// remoteFilename, along with the remote node it would replace.
+func (c *Client) uploadTransfer(fpath, remoteFilename string, info os.FileInfo) fileTransfer {
+	ft := fileTransfer{localPath: fpath, remotePath: remoteFilename, size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+	// the file may be stored in chunks.
+	var err error
+	if ft.node, err = c.findSilently(remoteFilename); err != nil {
+		ft.node, err = c.findSilently(remoteFilename + ChunkedSuffix)
+		ft.chunked = err == nil
+	}
+	if err != nil {
+		ft.node = nil
+	}
+
+	return ft
+}
 
-		log.Infof("uploading %q to %q", fpath, remoteFilename)
-		if remoteNode, err = c.NodeTree.MkdirAll(remotePath); err != nil {
+// uploadFile uploads the file ft into the remote folder, resolving the
+// conflict with the existing node ft.node, and reports its progress to
+// tracker.
+func (c *Client) uploadFile(ft fileTransfer, folder *node.Node, opts *UploadOptions, tracker *progressTracker) error {
+	log.Infof("uploading %q to %q", ft.localPath, ft.remotePath)
+	if isSpecial(ft.mode) {
+		log.Errorf("%s: %s", constants.ErrSpecialFile, ft.localPath)
+		return constants.ErrSpecialFile
+	}
+	f, err := openLocal(ft)
+	if err != nil {
+		return err
+	}
+	if closer, ok := f.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if ft.node != nil {
+		policy, err := c.resolveConflict(ft, f, opts, true)
+		if err != nil {
+			return err
+		}
+		if ft, err = c.applyConflict(ft, policy); err != nil {
 			return err
 		}
+		if policy == ConflictSkip {
+			log.Debugf("%q already exists, skipping", ft.localPath)
+			tracker.done(ft)
+			return nil
+		}
+	}
 
-		if f, err = os.Open(fpath); err != nil {
-			log.Errorf("%s: %s", constants.ErrOpenFile, fpath)
-			return constants.ErrOpenFile
+	if err := c.upload(folder, ft, f, opts, tracker); err != nil {
+		if err != constants.ErrNoContentsToUpload {
+			return err
 		}
-		defer f.Close()
+		tracker.done(ft)
+	}
 
-		// does the file already exist?
-		{
-			log.SetLevel(log.DisableLogLevel)
-			fileNode, err = c.NodeTree.FindNode(remoteFilename)
-			log.SetLevel(logLevel)
+	return nil
+}
+
+// applyConflict returns the file ft to upload once policy, as returned by
+// resolveConflict, is applied: a renamed file has a new path and no
+// existing node, an existing folder to overwrite is trashed.
+func (c *Client) applyConflict(ft fileTransfer, policy ConflictPolicy) (fileTransfer, error) {
+	switch {
+	case policy == ConflictRename:
+		ft.remotePath = c.renamedPath(ft.remotePath)
+		ft.node, ft.chunked = nil, false
+		log.Debugf("%q already exists, uploading it as %q", ft.localPath, ft.remotePath)
+	case policy == ConflictOverwrite && ft.node.IsDir():
+		log.Infof("%q is a folder, trashing it", ft.remotePath)
+		if err := c.NodeTree.RemoveNode(ft.node); err != nil {
+			return ft, err
 		}
-		if err == nil {
-			if fileNode.IsDir() {
-				log.Errorf("%s: remoteFilename %q", constants.ErrFileExistsAndIsFolder, remoteFilename)
-				return constants.ErrFileExistsAndIsFolder
-			}
-			hash := md5.New()
-			f.Seek(0, 0)
-			io.Copy(hash, f)
-			if hex.EncodeToString(hash.Sum(nil)) == fileNode.ContentProperties.MD5 {
-				log.Debugf("%q already exists and has the same content, skipping", fpath)
-				return nil
-			}
+		ft.node = nil
+	}
 
-			log.Debugf("%q already exists, overwrite is %t", fpath, overwrite)
-			if !overwrite {
-				log.Errorf("%s: remoteFilename %q", constants.ErrFileExistsWithDifferentContents, remoteFilename)
-				return constants.ErrFileExistsWithDifferentContents
-			}
+	return ft, nil
+}
+
+// upload uploads r, the content of the file ft, into folder then records its
+// attributes, see UploadOptions.Preserve, and the target of a symbolic link
+// stored as a marker. The existing node ft.node is overwritten, a chunked
+// file is replaced by a file and conversely depending on opts.ChunkSize.
+func (c *Client) upload(folder *node.Node, ft fileTransfer, r io.Reader, opts *UploadOptions, tracker *progressTracker) error {
+	if err := c.uploadContent(folder, ft, r, opts, tracker); err != nil {
+		return err
+	}
+	if err := c.recordAttributes(ft, opts); err != nil {
+		return err
+	}
+	if ft.mode&os.ModeSymlink != 0 {
+		return c.recordSymlink(ft)
+	}
+
+	return nil
+}
 
-			f.Seek(0, 0)
-			return fileNode.Overwrite(f)
+// uploadContent uploads r, the content of the file ft, into folder.
+func (c *Client) uploadContent(folder *node.Node, ft fileTransfer, r io.Reader, opts *UploadOptions, tracker *progressTracker) error {
+	if ra, ok := r.(io.ReaderAt); ok && opts.ChunkSize > 0 && ft.size > opts.ChunkSize {
+		if err := c.uploadChunked(folder, ft, ra, opts, tracker); err != nil {
+			return err
+		}
+		if ft.node != nil && !ft.chunked {
+			return c.NodeTree.RemoveNode(ft.node)
 		}
+		return nil
+	}
+	if ft.chunked {
+		// the file is no longer big enough to be chunked.
+		if _, err := c.uploadChunk(folder, ft.remotePath, tracker.reader(ft, r), opts); err != nil {
+			return err
+		}
+		tracker.done(ft)
+		return c.removeChunked(ft.remotePath)
+	}
 
-		f.Seek(0, 0)
-		if _, err := remoteNode.Upload(path.Base(fpath), f); err != nil && err != constants.ErrNoContentsToUpload {
+	content, err := c.encryptReader(ft.remotePath, tracker.reader(ft, r))
+	if err != nil {
+		return err
+	}
+	if ft.node != nil {
+		if _, err := c.uploadVerified(content, opts, overwrite(ft.node)); err != nil {
 			return err
 		}
+		tracker.done(ft)
+		return ft.node.AddLabels(opts.Labels...)
+	}
+	if _, err := c.uploadVerified(content, opts, create(folder, path.Base(c.encryptPath(ft.remotePath)), opts)); err != nil {
+		return err
+	}
+	tracker.done(ft)
+
+	return nil
+}
+
+// uploadVerified calls upload with r and returns the uploaded node. If the
+// content of the uploaded node does not match, it is uploaded again up to
+// opts.Retries times provided r can seek, then the node is trashed if
+// opts.TrashCorrupt is set.
+func (c *Client) uploadVerified(r io.Reader, opts *UploadOptions, upload func(io.Reader) (*node.Node, error)) (*node.Node, error) {
+	n, err := upload(r)
+	for i := 0; err == constants.ErrMD5Mismatch && i < opts.Retries; i++ {
+		seeker, ok := r.(io.Seeker)
+		if !ok {
+			break
+		}
+		if _, serr := seeker.Seek(0, 0); serr != nil {
+			break
+		}
+		log.Infof("the content of %q is corrupt, uploading it again", n.Name)
+		err = n.Overwrite(r)
+	}
+	if err == constants.ErrMD5Mismatch && opts.TrashCorrupt {
+		log.Infof("the content of %q is corrupt, trashing it", n.Name)
+		if terr := c.NodeTree.RemoveNode(n); terr != nil {
+			return nil, terr
+		}
+	}
+
+	return n, err
+}
+
+// create returns an upload function for uploadVerified creating the file name
+// in folder. A 409 Conflict is resolved according to opts.Deduplicate only if
+// the existing node has the content uploaded, a node of the same name being
+// an error whatever the policy.
+func create(folder *node.Node, name string, opts *UploadOptions) func(io.Reader) (*node.Node, error) {
+	return func(r io.Reader) (*node.Node, error) {
+		if opts.Deduplicate == DeduplicateNone {
+			return folder.UploadWithOptions(name, r, &node.UploadOptions{Metadata: opts.metadata()})
+		}
+
+		hash := md5.New()
+		n, err := folder.UploadWithOptions(name, io.TeeReader(r, hash), &node.UploadOptions{
+			Metadata:    opts.metadata(),
+			Deduplicate: true,
+		})
+		derr, ok := err.(*node.DuplicateError)
+		if !ok {
+			return n, err
+		}
+		// the upload may have stopped before the end of the content.
+		if _, err := io.Copy(hash, r); err != nil {
+			log.Errorf("%s: %s", constants.ErrReadingFileContents, err)
+			return nil, constants.ErrReadingFileContents
+		}
+		if derr.Node == nil || derr.Node.ContentProperties.MD5 != hex.EncodeToString(hash.Sum(nil)) {
+			return nil, derr
+		}
+
+		return opts.duplicate(folder, name, derr)
+	}
+}
+
+// overwrite returns an upload function for uploadVerified overwriting n.
+func overwrite(n *node.Node) func(io.Reader) (*node.Node, error) {
+	return func(r io.Reader) (*node.Node, error) {
+		return n, n.Overwrite(r)
+	}
+}
 
+// duplicate resolves the duplicate derr of the file name uploaded into folder,
+// an existing node with the same content, according to opts.Deduplicate and
+// returns the existing node.
+func (opts *UploadOptions) duplicate(folder *node.Node, name string, derr *node.DuplicateError) (*node.Node, error) {
+	if opts.Deduplicate == DeduplicateFail {
+		return nil, derr
+	}
+	if opts.Deduplicate == DeduplicateLink {
+		log.Infof("%s has the content of the node %s, linking it", name, derr.ID)
+		if err := derr.Node.AddParent(folder); err != nil {
+			return nil, err
+		}
+		return derr.Node, derr.Node.AddLabels(opts.Labels...)
+	}
+	log.Infof("%s has the content of the node %s, skipping it", name, derr.ID)
+
+	return derr.Node, nil
+}
+
+func (opts *UploadOptions) metadata() *node.Metadata {
+	if len(opts.Labels) == 0 {
 		return nil
 	}
+
+	return &node.Metadata{Labels: opts.Labels}
 }
@@ -0,0 +1,412 @@
+package acd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"gopkg.in/acd.v0/filter"
+	"gopkg.in/acd.v0/node"
+)
+
+func TestPrepareUpload(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	dir, err := ioutil.TempDir("", "acd-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(path.Join(dir, "pictures"), 0755)
+	for _, name := range []string{"README.md", "new.txt", "pictures/logo.png"} {
+		ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644)
+	}
+
+	tests := []struct {
+		recursive bool
+		want      map[string]bool
+	}{
+		{false, map[string]bool{"/README.md": true, "/new.txt": false}},
+		{true, map[string]bool{"/README.md": true, "/new.txt": false, "/pictures/logo.png": true}},
+	}
+	for _, test := range tests {
+		files, _, err := c.prepareUpload(dir, "/", &UploadOptions{Recursive: test.recursive})
+		if err != nil {
+			t.Fatalf("c.prepareUpload(%t) error: %s", test.recursive, err)
+		}
+		got := make(map[string]bool)
+		for _, f := range files {
+			got[f.remotePath] = f.node != nil
+			if want := path.Join(dir, f.remotePath); want != f.localPath {
+				t.Errorf("c.prepareUpload(%t) local path of %s: want %s got %s", test.recursive, f.remotePath, want, f.localPath)
+			}
+		}
+		if !reflect.DeepEqual(test.want, got) {
+			t.Errorf("c.prepareUpload(%t) remote paths and whether they exist: want %v got %v", test.recursive, test.want, got)
+		}
+	}
+}
+
+func TestPrepareUploadFilter(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	dir, err := ioutil.TempDir("", "acd-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(path.Join(dir, "pictures/raw"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path.Join(dir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"README.md":              "readme",
+		"notes.txt":              "notes",
+		"build/main.o":           "object",
+		"pictures/logo.png":      "logo",
+		"pictures/photo.jpg":     "photo",
+		"pictures/raw/photo.cr2": "raw",
+		"pictures/.acdignore":    "*.png\nraw/\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &filter.Filter{IgnoreFile: filter.DefaultIgnoreFile}
+	for _, pattern := range []string{"*.txt", "build/"} {
+		if err := f.Exclude(pattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uploaded, _, err := c.prepareUpload(dir, "/", &UploadOptions{Recursive: true, Filter: f})
+	if err != nil {
+		t.Fatalf("c.prepareUpload() error: %s", err)
+	}
+	var got []string
+	for _, ft := range uploaded {
+		got = append(got, ft.remotePath)
+	}
+	if want := []string{"/README.md", "/pictures/.acdignore", "/pictures/photo.jpg"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("c.prepareUpload() with a filter: want %v got %v", want, got)
+	}
+}
+
+func TestPrepareUploadSymlinks(t *testing.T) {
+	c := &Client{
+		NodeTree: node.Mocked,
+	}
+	dir, err := ioutil.TempDir("", "acd-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(path.Join(dir, "docs/empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "docs/guide.md"), []byte("guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"guide.md": "docs/guide.md",
+		"manual":   "docs",
+		"broken":   "missing",
+		"loop":     ".",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, path.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := syscall.Mkfifo(path.Join(dir, "fifo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts        *UploadOptions
+		wantFiles   []string
+		wantFolders []string
+	}{
+		{
+			&UploadOptions{Recursive: true},
+			[]string{"/docs/guide.md", "/guide.md", "/manual/guide.md"},
+			[]string{"/", "/docs", "/docs/empty", "/manual", "/manual/empty"},
+		},
+		{
+			&UploadOptions{Recursive: true, Symlinks: SymlinkSkip, ReportSpecial: true},
+			[]string{"/docs/guide.md", "/fifo"},
+			[]string{"/", "/docs", "/docs/empty"},
+		},
+		{
+			&UploadOptions{Recursive: true, Symlinks: SymlinkStore},
+			[]string{"/broken.acdsymlink", "/docs/guide.md", "/guide.md.acdsymlink", "/loop.acdsymlink", "/manual.acdsymlink"},
+			[]string{"/", "/docs", "/docs/empty"},
+		},
+	}
+	for _, test := range tests {
+		files, folders, err := c.prepareUpload(dir, "/", test.opts)
+		if err != nil {
+			t.Fatalf("c.prepareUpload(%d) error: %s", test.opts.Symlinks, err)
+		}
+		var got []string
+		for _, ft := range files {
+			got = append(got, ft.remotePath)
+		}
+		sort.Strings(got)
+		sort.Strings(folders)
+		if !reflect.DeepEqual(test.wantFiles, got) {
+			t.Errorf("c.prepareUpload(%d) files: want %v got %v", test.opts.Symlinks, test.wantFiles, got)
+		}
+		if !reflect.DeepEqual(test.wantFolders, folders) {
+			t.Errorf("c.prepareUpload(%d) folders: want %v got %v", test.opts.Symlinks, test.wantFolders, folders)
+		}
+	}
+}
+
+// newTestClient returns a Client talking to a test HTTP server serving the
+// nodes, the changes and the uploads with upload. The server must be closed.
+func newTestClient(t *testing.T, nodes []*node.Node, upload http.HandlerFunc) (*Client, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/nodes":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": nodes})
+		case r.URL.Path == "/changes":
+			fmt.Fprint(w, `{"end": true}`)
+		default:
+			upload(w, r)
+		}
+	}))
+	dir, err := ioutil.TempDir("", "acd-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Client{
+		config:      &Config{},
+		httpClient:  http.DefaultClient,
+		metadataURL: ts.URL + "/",
+		contentURL:  ts.URL + "/",
+	}
+	if c.NodeTree, err = node.NewTree(c, path.Join(dir, "cache")); err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+
+	return c, ts
+}
+
+func TestCreateDuplicate(t *testing.T) {
+	nodes := []*node.Node{
+		{ID: "root", Kind: "FOLDER", Status: "AVAILABLE"},
+		{ID: "folder", Name: "folder", Kind: "FOLDER", Status: "AVAILABLE", Parents: []string{"root"}},
+		{ID: "same-name", Name: "file", Kind: "FILE", Status: "AVAILABLE", Parents: []string{"folder"}, ContentProperties: node.ContentProperties{MD5: "9a0364b9e99bb480dd25e1f0284c8555"}},
+		{ID: "same-content", Name: "other", Kind: "FILE", Status: "AVAILABLE", Parents: []string{"root"}, ContentProperties: node.ContentProperties{MD5: "9a0364b9e99bb480dd25e1f0284c8555"}},
+	}
+	var existing string
+	c, ts := newTestClient(t, nodes, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, `{"message":"conflict","info":{"nodeId":%q}}`, existing)
+	})
+	defer ts.Close()
+	folder, err := c.NodeTree.FindNode("/folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		existing, content string
+		policy            DeduplicatePolicy
+		wantID            string
+	}{
+		// a node of the same name is an error whatever the policy.
+		{"same-name", "other content", DeduplicateNone, ""},
+		{"same-name", "other content", DeduplicateSkip, ""},
+		{"same-name", "other content", DeduplicateLink, ""},
+		// a node of the same content is resolved by the policy.
+		{"same-content", "content", DeduplicateNone, ""},
+		{"same-content", "content", DeduplicateFail, ""},
+		{"same-content", "content", DeduplicateSkip, "same-content"},
+	}
+	for _, test := range tests {
+		existing = test.existing
+		opts := &UploadOptions{Deduplicate: test.policy}
+		n, err := create(folder, "file", opts)(strings.NewReader(test.content))
+		if test.wantID == "" {
+			if _, ok := err.(*node.DuplicateError); !ok {
+				t.Errorf("create() over %s with policy %d: want a *node.DuplicateError got %v %v", test.existing, test.policy, n, err)
+			}
+			continue
+		}
+		if err != nil || n == nil || n.ID != test.wantID {
+			t.Errorf("create() over %s with policy %d: want the node %s got %v %v", test.existing, test.policy, test.wantID, n, err)
+		}
+	}
+}
+
+// fakeDrive is a test server keeping the nodes created, overwritten, renamed
+// and trashed by a client, concurrently.
+type fakeDrive struct {
+	mu     sync.Mutex
+	nodes  map[string]*node.Node
+	nextID int
+}
+
+func (d *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/nodes" && r.Header.Get("Content-Type") == "application/json":
+		var n node.Node
+		json.NewDecoder(r.Body).Decode(&n)
+		d.add(&n)
+		json.NewEncoder(w).Encode(&n)
+	case r.Method == "POST" && r.URL.Path == "/nodes":
+		var n node.Node
+		json.Unmarshal([]byte(d.content(r, &n)), &n)
+		d.add(&n)
+		json.NewEncoder(w).Encode(&n)
+	case r.Method == "PUT" && len(parts) == 3 && parts[2] == "content":
+		n := d.nodes[parts[1]]
+		d.content(r, n)
+		json.NewEncoder(w).Encode(n)
+	case r.Method == "PUT" && parts[0] == "trash":
+		d.nodes[parts[1]].Status = "TRASH"
+		fmt.Fprint(w, "{}")
+	case r.Method == "PATCH" && len(parts) == 2:
+		n := d.nodes[parts[1]]
+		json.NewDecoder(r.Body).Decode(n)
+		json.NewEncoder(w).Encode(n)
+	default:
+		http.Error(w, r.Method+" "+r.URL.Path, http.StatusNotImplemented)
+	}
+}
+
+// add gives the new node n an ID and keeps it.
+func (d *fakeDrive) add(n *node.Node) {
+	d.nextID++
+	n.ID, n.Status = fmt.Sprintf("id%d", d.nextID), "AVAILABLE"
+	d.nodes[n.ID] = n
+}
+
+// content sets the content properties of n from the content uploaded by r and
+// returns its metadata field.
+func (d *fakeDrive) content(r *http.Request, n *node.Node) string {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		return ""
+	}
+	f, _, err := r.FormFile("content")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	hash := md5.New()
+	size, _ := io.Copy(hash, f)
+	n.ContentProperties.MD5, n.ContentProperties.Size = hex.EncodeToString(hash.Sum(nil)), uint64(size)
+
+	return r.FormValue("metadata")
+}
+
+func TestUploadFolderJobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acd-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(path.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var local []string
+	for i := 0; i < 8; i++ {
+		local = append(local, fmt.Sprintf("f%d", i), fmt.Sprintf("sub/g%d", i))
+	}
+	for _, name := range local {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte("local "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	md5Of := func(content string) string {
+		sum := md5.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		opts *UploadOptions
+		// renamed is set if the conflicting files are uploaded next to the
+		// existing ones.
+		renamed bool
+	}{
+		{&UploadOptions{Conflict: ConflictOverwrite, FolderConflict: ConflictOverwrite}, false},
+		{&UploadOptions{Overwrite: true, FolderConflict: ConflictOverwrite}, false},
+		{&UploadOptions{Conflict: ConflictRename, FolderConflict: ConflictRename}, true},
+	}
+	for _, test := range tests {
+		// f0 and sub/g0 differ, f1 is the same, f2 is a folder and sub is
+		// there already.
+		drive := &fakeDrive{nodes: make(map[string]*node.Node)}
+		for _, n := range []*node.Node{
+			{ID: "root", Kind: "FOLDER"},
+			{ID: "dest", Name: "dest", Kind: "FOLDER", Parents: []string{"root"}},
+			{ID: "f0", Name: "f0", Kind: "FILE", Parents: []string{"dest"}, ContentProperties: node.ContentProperties{MD5: md5Of("remote f0")}},
+			{ID: "f1", Name: "f1", Kind: "FILE", Parents: []string{"dest"}, ContentProperties: node.ContentProperties{MD5: md5Of("local f1")}},
+			{ID: "f2", Name: "f2", Kind: "FOLDER", Parents: []string{"dest"}},
+			{ID: "f2-child", Name: "child", Kind: "FILE", Parents: []string{"f2"}},
+			{ID: "sub", Name: "sub", Kind: "FOLDER", Parents: []string{"dest"}},
+			{ID: "g0", Name: "g0", Kind: "FILE", Parents: []string{"sub"}, ContentProperties: node.ContentProperties{MD5: md5Of("remote g0")}},
+		} {
+			n.Status = "AVAILABLE"
+			drive.nodes[n.ID] = n
+		}
+		var nodes []*node.Node
+		for _, n := range drive.nodes {
+			copied := *n
+			nodes = append(nodes, &copied)
+		}
+		c, ts := newTestClient(t, nodes, drive.ServeHTTP)
+
+		test.opts.Recursive, test.opts.Jobs = true, 4
+		if err := c.UploadFolderWithOptions(dir, "/dest", test.opts); err != nil {
+			t.Errorf("c.UploadFolderWithOptions(%+v) error: %v", test.opts, err)
+		}
+		ts.Close()
+
+		for _, name := range local {
+			remotePath := "/dest/" + name
+			if test.renamed && (name == "f0" || name == "f1" || name == "f2" || name == "sub/g0") {
+				remotePath += " (1)"
+			}
+			n, err := c.NodeTree.FindNode(remotePath)
+			if err != nil {
+				t.Errorf("c.UploadFolderWithOptions(%+v): %s was not uploaded", test.opts, remotePath)
+				continue
+			}
+			if want, got := md5Of("local "+name), n.ContentProperties.MD5; want != got {
+				t.Errorf("c.UploadFolderWithOptions(%+v) MD5 of %s: want %s got %s", test.opts, remotePath, want, got)
+			}
+		}
+		if want, got := !test.renamed, drive.nodes["f2"].Status == "TRASH"; want != got {
+			t.Errorf("c.UploadFolderWithOptions(%+v): want the folder f2 trashed %t got %t", test.opts, want, got)
+		}
+	}
+}